@@ -1,14 +1,16 @@
 package goapi;
 
 import (
+   "errors"
    "fmt"
    "io"
    "io/ioutil"
    "mime/multipart"
    "net/http"
    "reflect"
+   "regexp"
    "runtime"
-   "strconv"
+   "runtime/debug"
    "strings"
    "time"
 )
@@ -18,12 +20,61 @@ const (
    PARAM_TOKEN = "token"
 )
 
+var knownHTTPMethods map[string]bool = map[string]bool{
+   http.MethodGet: true,
+   http.MethodHead: true,
+   http.MethodPost: true,
+   http.MethodPut: true,
+   http.MethodPatch: true,
+   http.MethodDelete: true,
+   http.MethodConnect: true,
+   http.MethodOptions: true,
+   http.MethodTrace: true,
+};
+
 const (
    API_PARAM_TYPE_INT = iota
    API_PARAM_TYPE_STRING
    API_PARAM_TYPE_FILE
+   // The entire JSON request body, decoded into the type of the handler's argument.
+   API_PARAM_TYPE_JSON
+   // "true" or "false" (any case).
+   API_PARAM_TYPE_BOOL
+   // float64.
+   API_PARAM_TYPE_FLOAT
+   API_PARAM_TYPE_INT64
+   API_PARAM_TYPE_UINT
+   // time.Time, as an RFC 3339 timestamp (eg "2006-01-02T15:04:05Z").
+   API_PARAM_TYPE_TIME
+   // time.Duration, as a Go duration string (eg "1h30m").
+   API_PARAM_TYPE_DURATION
 )
 
+// Where the value for a param comes from.
+const (
+   // Query parameters or POST form values.
+   API_PARAM_SOURCE_ANY = iota
+   // A placeholder in the ApiMethod's path (eg "/users/{id}").
+   API_PARAM_SOURCE_PATH
+   // Only query parameters.
+   API_PARAM_SOURCE_QUERY
+   // Only values from the request body (url-encoded or multipart forms).
+   API_PARAM_SOURCE_BODY
+   // An HTTP header, the param's name is the header's name (eg "X-Client-Version").
+   API_PARAM_SOURCE_HEADER
+   // A cookie, the param's name is the cookie's name.
+   API_PARAM_SOURCE_COOKIE
+)
+
+var paramSourceNames map[int]string = map[int]string{
+   API_PARAM_SOURCE_ANY: "any",
+   API_PARAM_SOURCE_PATH: "path",
+   API_PARAM_SOURCE_QUERY: "query",
+   API_PARAM_SOURCE_BODY: "body",
+   API_PARAM_SOURCE_HEADER: "header",
+   API_PARAM_SOURCE_COOKIE: "cookie",
+};
+
 // We need to define these as types, so we can figure it out when we want to pass params.
 type Token string;
 type UserId int;
@@ -47,21 +98,85 @@ func (file File) Data() ([]byte, error) {
 
 type ApiMethod struct {
    path string
+   template pathTemplate
    handler interface{}
    auth bool
+   // Allow requests without any credential (see SetOptionalAuth()).
+   optionalAuth bool
    allowTokenParam bool
+   // Nil for the defaults (see extractor.go).
+   tokenExtractors []TokenExtractor
    params []ApiMethodParam
    log Logger
    serializer Serializer
    contentType string
    errorResponder ErrorResponder
-   tokenValidator ValidateToken
+   principalValidator ValidatePrincipal
+   // Validates Basic auth credentials (see basic.go).
+   basicAuthValidator ValidateBasicAuth
+   basicAuthRealm string
+   // The principal must have all of these (see auth.go).
+   requiredRoles []string
+   requiredScopes []string
+   panicReporter PanicReporter
+   // Custom implicit params, keyed by type (see injector.go).
+   injectors map[reflect.Type]injector
+   // The most bytes of a JSON body to read (0 for no limit), see jsonbody.go.
+   maxBodySize int64
+   // The handler's context will be canceled after this long (0 for no timeout), see timeout.go.
+   timeout time.Duration
+   allowedMethods []string
+   // Only set if the handler takes a params struct (see structparams.go).
+   paramsStruct reflect.Type
+   // The field index (in the params struct) for each param.
+   structFields []int
+   // Only set for methods made with NewTypedApiMethod().
+   // Typed handlers are invoked directly (without reflection).
+   typedHandler func(method ApiMethod, response http.ResponseWriter, request *http.Request) (interface{}, int, string, error)
 }
 
 type ApiMethodParam struct {
    Name string
    ParamType int
    Required bool
+   Source int
+   // Normally an empty string is treated as a missing param.
+   // If set, a string param that was sent empty is passed along as "" (only for API_PARAM_TYPE_STRING).
+   AllowEmpty bool
+   // The value to use when a non-required param is missing (nil for no default).
+   // Must be the exact type the handler uses for the param's type (eg int for API_PARAM_TYPE_INT).
+   // Files and JSON params cannot have defaults.
+   Default interface{}
+   // Collect every value sent for the param (eg "?tag=a&tag=b") into a slice.
+   // Only for scalar types, ParamType is the type of each element (so the handler takes []string for API_PARAM_TYPE_STRING).
+   // A list's Default must also be a slice (eg []int{1, 2}).
+   List bool
+   // For lists, also split each value on commas (eg "?tag=a,b").
+   SplitCommas bool
+
+   // Constraints that a value must follow before the handler is called (see constraints.go).
+   // Violations are reported as ParamErrors.
+
+   // Inclusive bounds for numeric, time, and duration params (nil for no bound).
+   // Must be the exact type the handler uses for the param's type (like Default).
+   Min interface{}
+   Max interface{}
+   // Inclusive bounds on the number of characters in a string param (0 for no bound).
+   MinLength int
+   MaxLength int
+   // A regular expression that a string param must match (use ^ and $ to match the entire value).
+   Pattern string
+   // The only values allowed for the param (nil for any value).
+   // Each value is parsed like a request value would be (so "1" for an int param).
+   Enum []string
+   // Inclusive bounds on the number of values in a list (0 for no bound).
+   // Like all constraints, these are only checked when the param is sent.
+   MinCount int
+   MaxCount int
+
+   // Prepared versions of the constraints (see prepareConstraints()).
+   pattern *regexp.Regexp
+   enumValues []interface{}
 }
 
 func (method ApiMethod) Path() string {
@@ -74,6 +189,85 @@ func (method *ApiMethod) SetAllowTokenParam(val bool) *ApiMethod {
    return method;
 }
 
+// Restrict the HTTP methods (verbs) that this ApiMethod will answer to.
+// Requests using any other method will get a 405 (Method Not Allowed).
+// OPTIONS is always allowed (for preflight checks) and HEAD is allowed whenever GET is.
+// If never called (or called with no methods), all methods are allowed.
+// Will panic on an unknown method or if OPTIONS is the only method.
+// Returns this so you can chain.
+func (method *ApiMethod) SetAllowedMethods(methods ...string) *ApiMethod {
+   if (len(methods) == 0) {
+      method.allowedMethods = nil;
+      return method;
+   }
+
+   method.allowedMethods = make([]string, 0, len(methods));
+
+   for _, httpMethod := range(methods) {
+      httpMethod = strings.ToUpper(strings.TrimSpace(httpMethod));
+
+      if (!knownHTTPMethods[httpMethod]) {
+         method.log.Panic(fmt.Sprintf("API handler (%s) has an unknown allowed HTTP method (%s)", method.path, httpMethod));
+      }
+
+      if (httpMethod == http.MethodOptions || method.allowsMethod(httpMethod)) {
+         continue;
+      }
+
+      method.allowedMethods = append(method.allowedMethods, httpMethod);
+   }
+
+   if (len(method.allowedMethods) == 0) {
+      method.log.Panic(fmt.Sprintf("API handler (%s) only allows OPTIONS (which is always allowed), give at least one other method", method.path));
+   }
+
+   return method;
+}
+
+// Will a request with |httpMethod| be handled (not including OPTIONS).
+func (method ApiMethod) allowsMethod(httpMethod string) bool {
+   if (method.allowedMethods == nil) {
+      return true;
+   }
+
+   for _, allowedMethod := range(method.allowedMethods) {
+      if (allowedMethod == httpMethod || (allowedMethod == http.MethodGet && httpMethod == http.MethodHead)) {
+         return true;
+      }
+   }
+
+   return false;
+}
+
+// The value for the "Allow" and "Access-Control-Allow-Methods" headers.
+func (method ApiMethod) allowHeader() string {
+   if (method.allowedMethods == nil) {
+      return "POST, GET, OPTIONS";
+   }
+
+   var methods []string = make([]string, 0, len(method.allowedMethods) + 2);
+   for _, allowedMethod := range(method.allowedMethods) {
+      methods = append(methods, allowedMethod);
+
+      if (allowedMethod == http.MethodGet && !method.hasAllowedMethod(http.MethodHead)) {
+         methods = append(methods, http.MethodHead);
+      }
+   }
+   methods = append(methods, http.MethodOptions);
+
+   return strings.Join(methods, ", ");
+}
+
+func (method ApiMethod) hasAllowedMethod(httpMethod string) bool {
+   for _, allowedMethod := range(method.allowedMethods) {
+      if (allowedMethod == httpMethod) {
+         return true;
+      }
+   }
+
+   return false;
+}
+
 // Will just panic on error.
 func (method ApiMethod) validate() {
    // Check the definitions.
@@ -85,14 +279,55 @@ func (method ApiMethod) validate() {
       method.log.Panic(fmt.Sprintf("Nil handler for API handler for path: %s", method.path));
    }
 
+   var numJSONParams int = 0;
    for _, param := range(method.params) {
       if (param.Name == "") {
          method.log.Panic(fmt.Sprintf("Empty name for param for API handler for path: %s", method.path));
       }
 
-      if (!(param.ParamType == API_PARAM_TYPE_INT || param.ParamType == API_PARAM_TYPE_STRING || param.ParamType == API_PARAM_TYPE_FILE)) {
+      _, ok := paramTypes[param.ParamType];
+      if (!ok) {
          method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has bad type (%d)", param.Name, method.path, param.ParamType));
       }
+
+      _, ok = paramSourceNames[param.Source];
+      if (!ok) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has bad source (%d)", param.Name, method.path, param.Source));
+      }
+
+      if ((param.ParamType == API_PARAM_TYPE_FILE || param.ParamType == API_PARAM_TYPE_JSON) && !(param.Source == API_PARAM_SOURCE_ANY || param.Source == API_PARAM_SOURCE_BODY)) {
+         method.log.Panic(fmt.Sprintf("File/JSON param (%s) for API handler (%s) can only come from the request body", param.Name, method.path));
+      }
+
+      if (param.ParamType == API_PARAM_TYPE_JSON) {
+         numJSONParams++;
+      }
+
+      if (param.AllowEmpty && param.ParamType != API_PARAM_TYPE_STRING) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) allows empty values, but is not a string", param.Name, method.path));
+      }
+
+      if (param.List && !isScalarParamType(param.ParamType)) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) is a list, but is a file or JSON", param.Name, method.path));
+      }
+
+      if (param.SplitCommas && !param.List) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) splits on commas, but is not a list", param.Name, method.path));
+      }
+
+      method.validateDefault(param);
+      method.validateConstraints(param);
+   }
+
+   if (numJSONParams > 1) {
+      method.log.Panic(fmt.Sprintf("API handler (%s) has more than one JSON body param", method.path));
+   }
+
+   method.validatePathParams();
+
+   // Typed handlers have already been checked by the compiler.
+   if (method.typedHandler != nil) {
+      return;
    }
 
    // Check parameter semantics.
@@ -101,6 +336,7 @@ func (method ApiMethod) validate() {
 
    var numParams int = handlerType.NumIn();
    var additionalParams = 0;
+   var apiParamIndex = 0;
 
    for i := 0; i < numParams; i++ {
       var ParamType reflect.Type = handlerType.In(i);
@@ -123,19 +359,56 @@ func (method ApiMethod) validate() {
          if (!method.auth) {
             method.log.Panic(fmt.Sprintf("API handler (%s) requested a user name without authentication", method.path));
          }
+      } else if (ParamType.String() == "*goapi.Principal") {
+         additionalParams++;
+
+         if (!method.auth) {
+            method.log.Panic(fmt.Sprintf("API handler (%s) requested a principal without authentication", method.path));
+         }
       } else if (ParamType.String() == "*http.Request") {
          additionalParams++;
       } else if (ParamType.String() == "http.ResponseWriter") {
          additionalParams++;
+      } else if (ParamType.String() == "context.Context") {
+         additionalParams++;
+      } else if (method.paramsStruct != nil && ParamType == method.paramsStruct) {
+         // The params were derived from the struct's fields, so they already match.
+         apiParamIndex += len(method.structFields);
+      } else if (method.hasInjector(ParamType)) {
+         additionalParams++;
+      } else if (apiParamIndex < len(method.params) && method.params[apiParamIndex].ParamType == API_PARAM_TYPE_JSON) {
+         // JSON bodies can be decoded into most types.
+         if (ParamType.Kind() == reflect.Func || ParamType.Kind() == reflect.Chan || ParamType.Kind() == reflect.UnsafePointer) {
+            method.log.Panic(fmt.Sprintf("API handler (%s) has a JSON parameter with a type (%s) that cannot be decoded into", method.path, ParamType.String()));
+         }
+
+         apiParamIndex++;
       } else {
-         if (!(ParamType.String() == "int" || ParamType.String() == "string" || ParamType.String() == "goapi.File")) {
-            method.log.Panic(fmt.Sprintf("API handler (%s) has an actual parameter with incorrect type (%s) must be string or int", method.path, ParamType.String()));
+         // The rest of the types must match exactly.
+         // If there are too many parameters, then the count check will catch it.
+         if (apiParamIndex < len(method.params)) {
+            var param ApiMethodParam = method.params[apiParamIndex];
+            var expectedType reflect.Type = param.valueType();
+
+            // Scalars may also be pointers (which will be nil when the param is missing).
+            // Lists are already nil when missing.
+            if (ParamType != expectedType && !(isScalarParamType(param.ParamType) && !param.List && ParamType == reflect.PointerTo(expectedType))) {
+               method.log.Panic(fmt.Sprintf("API handler (%s) has an actual parameter with incorrect type (%s), param (%s) must be %s", method.path, ParamType.String(), param.Name, expectedType.String()));
+            }
          }
+
+         apiParamIndex++;
       }
    }
 
-   if (numParams != len(method.params) + additionalParams) {
-      method.log.Panic(fmt.Sprintf("API handler (%s) actually expects %d parameters, but is defined to expect %d (%d defined, %d implicit)", method.path, numParams, len(method.params) + additionalParams, len(method.params), additionalParams));
+   // A params struct takes the place of all the defined params.
+   var definedParams int = len(method.params);
+   if (method.paramsStruct != nil) {
+      definedParams = 1;
+   }
+
+   if (numParams != definedParams + additionalParams) {
+      method.log.Panic(fmt.Sprintf("API handler (%s) actually expects %d parameters, but is defined to expect %d (%d defined, %d implicit)", method.path, numParams, definedParams + additionalParams, definedParams, additionalParams));
    }
 
    // Check the return semantics.
@@ -161,10 +434,81 @@ func (method ApiMethod) validate() {
    }
 }
 
+// Is |argType| one of the built-in implicit param types.
+func isImplicitType(argType reflect.Type) bool {
+   switch argType.String() {
+   case "goapi.Token", "goapi.UserId", "goapi.UserName", "*goapi.Principal", "*http.Request", "http.ResponseWriter", "context.Context":
+      return true;
+   default:
+      return false;
+   }
+}
+
+func (method ApiMethod) hasInjector(argType reflect.Type) bool {
+   _, ok := method.injectors[argType];
+   return ok;
+}
+
+func (method ApiMethod) validateDefault(param ApiMethodParam) {
+   if (param.Default == nil) {
+      return;
+   }
+
+   if (param.Required) {
+      method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) is required, but has a default", param.Name, method.path));
+   }
+
+   if (!isScalarParamType(param.ParamType)) {
+      method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) is a file or JSON, but has a default", param.Name, method.path));
+   }
+
+   var expectedType reflect.Type = param.valueType();
+   if (reflect.TypeOf(param.Default) != expectedType) {
+      method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has a default with the wrong type (%s), must be %s", param.Name, method.path, reflect.TypeOf(param.Default).String(), expectedType.String()));
+   }
+}
+
+// Every placeholder in the path must have a matching path param and vice versa.
+func (method ApiMethod) validatePathParams() {
+   var pathParams map[string]ApiMethodParam = make(map[string]ApiMethodParam);
+   for _, param := range(method.params) {
+      if (param.Source != API_PARAM_SOURCE_PATH) {
+         continue;
+      }
+
+      pathParams[param.Name] = param;
+   }
+
+   for _, name := range(method.template.placeholders()) {
+      _, ok := pathParams[name];
+      if (!ok) {
+         method.log.Panic(fmt.Sprintf("API handler (%s) has a path placeholder ({%s}) without a matching path param", method.path, name));
+      }
+
+      delete(pathParams, name);
+   }
+
+   for name := range(pathParams) {
+      method.log.Panic(fmt.Sprintf("Path param (%s) for API handler (%s) does not have a matching placeholder in the path", name, method.path));
+   }
+}
+
 func (method ApiMethod) Middleware() func(response http.ResponseWriter, request *http.Request) {
    return func(response http.ResponseWriter, request *http.Request) {
-      // Skip preflight checks.
-      if (request.Method == "OPTIONS") {
+      // Any panics (in the handler or otherwise) will get a 500.
+      defer method.recoverPanic(response, request);
+
+      // Preflight checks only need the headers.
+      if (request.Method == http.MethodOptions) {
+         method.setStandardHeaders(response);
+         response.Header().Set("Allow", method.allowHeader());
+         return;
+      }
+
+      if (!method.allowsMethod(request.Method)) {
+         method.setStandardHeaders(response);
+         response.Header().Set("Allow", method.allowHeader());
+         method.sendErrorResponse(nil, http.StatusMethodNotAllowed, response, request);
          return;
       }
 
@@ -172,86 +516,128 @@ func (method ApiMethod) Middleware() func(response http.ResponseWriter, request
          method.log.Debug(request.URL.String());
       }
 
-      responseObj, httpStatus, contentType, err := method.handleAPIRequest(response, request);
-      response.Header().Set("Content-Type", contentType);
-
-      if (err != nil) {
-         method.sendResponse("", err, httpStatus, response);
+      if (method.timeout > 0) {
+         method.serveWithTimeout(response, request);
          return;
       }
 
-      // Check to see if we need to close the caller's response object when we are done.
-      defer func() {
-         closer, ok := responseObj.(io.Closer);
-         if (ok) {
-            err = closer.Close();
-            if (err != nil) {
-               method.log.WarnE("Error closing a response reader, but the response still went out fine.", err);
-            }
-         }
-      }();
+      method.serve(response, request);
+   }
+}
 
-      // If the response object is an io.ReadSeeker, then we will let
-      // http.ServeContent take care of almost all the work
-      // (except setting the content type).
-      readSeeker, ok := responseObj.(io.ReadSeeker);
-      if (ok) {
-         http.ServeContent(response, request, "", time.Time{}, readSeeker);
-         return;
+// Handle the API side of the request and write the response.
+func (method ApiMethod) serve(response http.ResponseWriter, request *http.Request) {
+   responseObj, httpStatus, contentType, cleanup, err := method.handleAPIRequest(response, request);
+
+   // Injected values are only cleaned up once the response is completely written (streamed responses may still need them).
+   var handlerErr error = err;
+   defer func() {
+      recovered := recover();
+      if (recovered != nil) {
+         cleanup(PanicError{recovered, debug.Stack()});
+         panic(recovered);
       }
 
-      // Set some standard headers.
-      response.Header().Set("Access-Control-Allow-Origin", "*");
-      response.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS");
-      response.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization");
+      // The client already got a 503 for a handler that finished too late, so its work should not be kept.
+      if (!finishedInTime(response)) {
+         handlerErr = request.Context().Err();
+      }
 
-      // If the response object is a reader, then stream it into the response writer.
-      reader, ok := responseObj.(io.Reader);
-      if (ok) {
-         if (httpStatus == 0) {
-            httpStatus = http.StatusOK;
-         }
+      cleanup(handlerErr);
+   }();
+
+   response.Header().Set("Content-Type", contentType);
 
-         response.WriteHeader(httpStatus);
+   if (err != nil) {
+      method.sendResponse("", err, httpStatus, response, request);
+      return;
+   }
 
-         _, err = io.Copy(response, reader);
+   // Check to see if we need to close the caller's response object when we are done.
+   defer func() {
+      closer, ok := responseObj.(io.Closer);
+      if (ok) {
+         err = closer.Close();
          if (err != nil) {
-            // The reeponse may have got partially written... so just abandon the request.
-            method.log.ErrorE("Failed to stream the response", err);
+            method.log.WarnE("Error closing a response reader, but the response still went out fine.", err);
          }
-      } else {
-         // Otherwise, just serialize the response and send it over.
-         responseString, err := method.serializer(responseObj);
-         method.sendResponse(responseString, err, httpStatus, response);
       }
+   }();
+
+   // If the response object is an io.ReadSeeker, then we will let
+   // http.ServeContent take care of almost all the work
+   // (except setting the content type).
+   readSeeker, ok := responseObj.(io.ReadSeeker);
+   if (ok) {
+      http.ServeContent(response, request, "", time.Time{}, readSeeker);
+      return;
    }
+
+   method.setStandardHeaders(response);
+
+   // If the response object is a reader, then stream it into the response writer.
+   reader, ok := responseObj.(io.Reader);
+   if (ok) {
+      if (httpStatus == 0) {
+         httpStatus = http.StatusOK;
+      }
+
+      response.WriteHeader(httpStatus);
+
+      _, err = io.Copy(response, reader);
+      if (err != nil) {
+         // The reeponse may have got partially written... so just abandon the request.
+         method.log.ErrorE("Failed to stream the response", err);
+      }
+   } else {
+      // Otherwise, just serialize the response and send it over.
+      responseString, err := method.serializer(responseObj);
+      method.sendResponse(responseString, err, httpStatus, response, request);
+   }
+}
+
+// Set some standard (CORS) headers.
+func (method ApiMethod) setStandardHeaders(response http.ResponseWriter) {
+   response.Header().Set("Access-Control-Allow-Origin", "*");
+   response.Header().Set("Access-Control-Allow-Methods", method.allowHeader());
+   response.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization");
 }
 
 // This handles the API side of the request.
 // None of the boilerplate.
-func (method ApiMethod) handleAPIRequest(response http.ResponseWriter, request *http.Request) (interface{}, int, string, error) {
+// The returned cleanup (never nil) cleans up any injected values (see injector), call it once the response is written.
+func (method ApiMethod) handleAPIRequest(response http.ResponseWriter, request *http.Request) (interface{}, int, string, func(err error), error) {
    var userId int = -1;
    var userName string = "";
-   var ok bool;
    var token string = "";
+   var principal *Principal = nil;
 
    if (method.auth) {
-      var responseObject interface{};
-      ok, userId, userName, token, responseObject = method.authRequest(request);
+      ok, authPrincipal, responseObject, httpStatus := method.authRequest(response, request);
       if (!ok) {
-         return responseObject, http.StatusUnauthorized, method.contentType, nil;
+         return responseObject, httpStatus, errorContentType(responseObject, method.contentType), noCleanup, nil;
+      }
+
+      // Anonymous requests (see SetOptionalAuth()) do not have a principal.
+      if (authPrincipal != nil) {
+         principal = authPrincipal;
+         userId, userName, token = principal.UserId, principal.UserName, principal.Token;
+         request = withAuthContext(request, principal);
       }
    }
 
-   ok, args := method.createArguments(UserId(userId), UserName(userName), Token(token), response, request);
-   if (!ok) {
-      return method.errorResponder(nil, http.StatusBadRequest), http.StatusBadRequest, method.contentType, nil;
+   if (method.typedHandler != nil) {
+      responseObj, httpStatus, contentType, err := method.typedHandler(method, response, request);
+      return responseObj, httpStatus, contentType, noCleanup, err;
    }
 
-   var handlerValue reflect.Value = reflect.ValueOf(method.handler);
-   returns := handlerValue.Call(args);
+   args, err := method.createArguments(UserId(userId), UserName(userName), Token(token), principal, response, request);
+   if (err != nil) {
+      responseObj, httpStatus, contentType, err := method.badRequestResponse(err, request);
+      return responseObj, httpStatus, contentType, noCleanup, err;
+   }
 
-   return method.createReturnValues(returns);
+   return method.callWithInjectors(args, request);
 }
 
 func (method ApiMethod) createReturnValues(returns []reflect.Value) (interface{}, int, string, error) {
@@ -283,13 +669,21 @@ func (method ApiMethod) createReturnValues(returns []reflect.Value) (interface{}
       }
    }
 
+   // An explicitly returned status beats the APIError's.
+   if (err != nil && httpStatus == 0) {
+      httpStatus = errorStatus(err);
+   }
+
    return responseObj, httpStatus, contentType, err;
 }
 
 // Get all the parameters setup for invocation.
-func (method ApiMethod) createArguments(userId UserId, userName UserName, token Token, response http.ResponseWriter, request *http.Request) (bool, []reflect.Value) {
+// All params are checked (even after one fails) so that every failure can be reported at once (as ParamErrors).
+func (method ApiMethod) createArguments(userId UserId, userName UserName, token Token, principal *Principal, response http.ResponseWriter, request *http.Request) ([]reflect.Value, error) {
    var handlerType reflect.Type = reflect.TypeOf(method.handler);
    var numParams int = handlerType.NumIn();
+   var body *jsonBody = method.newJSONBody(request);
+   var errs ParamErrors = nil;
 
    var apiParamIndex = 0;
    var paramValues []reflect.Value = make([]reflect.Value, numParams);
@@ -297,22 +691,36 @@ func (method ApiMethod) createArguments(userId UserId, userName UserName, token
    for i := 0; i < numParams; i++ {
       var ParamType reflect.Type = handlerType.In(i);
 
-      // The user id, token, request, and response get handled specially.
+      // The user id, token, principal, request, response, and context get handled specially.
       if (method.auth && ParamType.String() == "goapi.Token") {
          paramValues[i] = reflect.ValueOf(token);
       } else if (method.auth && ParamType.String() == "goapi.UserId") {
          paramValues[i] = reflect.ValueOf(userId);
       } else if (method.auth && ParamType.String() == "goapi.UserName") {
          paramValues[i] = reflect.ValueOf(userName);
+      } else if (method.auth && ParamType.String() == "*goapi.Principal") {
+         paramValues[i] = reflect.ValueOf(principal);
       } else if (ParamType.String() == "*http.Request") {
          paramValues[i] = reflect.ValueOf(request);
       } else if (ParamType.String() == "http.ResponseWriter") {
          paramValues[i] = reflect.ValueOf(response);
+      } else if (ParamType.String() == "context.Context") {
+         paramValues[i] = reflect.ValueOf(request.Context());
+      } else if (method.paramsStruct != nil && ParamType == method.paramsStruct) {
+         var structValue reflect.Value = reflect.New(ParamType).Elem();
+
+         errs = append(errs, method.fillParamsStruct(structValue, apiParamIndex, request, body)...);
+
+         paramValues[i] = structValue;
+         apiParamIndex += len(method.structFields);
+      } else if (method.hasInjector(ParamType)) {
+         // Injected values are only built once all the params are good (see injectArguments()).
+         continue;
       } else {
          // Normal param, fetch the next api parameter and pass it along.
-         ok, val := method.fetchParam(apiParamIndex, request);
-         if (!ok) {
-            return false, []reflect.Value{};
+         val, paramErrs := method.fetchParam(apiParamIndex, ParamType, request, body);
+         if (paramErrs != nil) {
+            errs = append(errs, paramErrs...);
          }
 
          paramValues[i] = val;
@@ -320,71 +728,327 @@ func (method ApiMethod) createArguments(userId UserId, userName UserName, token
       }
    }
 
-   return true, paramValues;
+   // An oversized body is the only problem worth reporting.
+   if (body.tooLarge()) {
+      return []reflect.Value{}, body.err;
+   }
+
+   if (len(errs) > 0) {
+      return []reflect.Value{}, errs;
+   }
+
+   return paramValues, nil;
 }
 
-func (method ApiMethod) fetchParam(apiParamIndex int, request *http.Request) (bool, reflect.Value) {
-   // Only the first call will do anything.
-   request.ParseMultipartForm(MULTIPART_PARSE_SIZE);
+// Fill in all the fields of a params struct.
+// The struct's params start at |apiParamIndex|.
+// Returns all the params that failed.
+func (method ApiMethod) fillParamsStruct(structValue reflect.Value, apiParamIndex int, request *http.Request, body *jsonBody) ParamErrors {
+   var errs ParamErrors = nil;
+
+   for _, fieldIndex := range(method.structFields) {
+      var field reflect.Value = structValue.Field(fieldIndex);
+
+      val, paramErrs := method.fetchParam(apiParamIndex, field.Type(), request, body);
+      if (paramErrs != nil) {
+         errs = append(errs, paramErrs...);
+      } else {
+         field.Set(val);
+      }
+
+      apiParamIndex++;
+   }
 
+   return errs;
+}
+
+// |argType| is the type of the handler's argument.
+// Scalar params may have a pointer type, which will get nil when the param is missing.
+// Any non-nil errors (even empty ones, see jsonBody.badJSONErrors()) mean the param failed.
+func (method ApiMethod) fetchParam(apiParamIndex int, argType reflect.Type, request *http.Request, body *jsonBody) (reflect.Value, ParamErrors) {
    var param ApiMethodParam = method.params[apiParamIndex];
 
+   if (param.ParamType == API_PARAM_TYPE_JSON) {
+      var ok bool = false;
+      var value reflect.Value = reflect.New(argType).Elem();
+      var err error = nil;
+
+      if (isJSONRequest(request)) {
+         ok, value, err = body.decode(argType);
+         if (err != nil) {
+            method.log.WarnE(fmt.Sprintf("Unable to decode JSON body parameter (%s)", param.Name), err);
+            return reflect.Value{}, body.badJSONErrors(param);
+         }
+      }
+
+      if (!ok && param.Required) {
+         method.log.Warn(fmt.Sprintf("Required JSON body parameter not found: %s", param.Name));
+         return reflect.Value{}, ParamErrors{*param.missingError()};
+      }
+
+      return value, nil;
+   }
+
+   // Only the first call will do anything.
+   // Non-form bodies (eg JSON) will not be consumed.
+   request.ParseMultipartForm(MULTIPART_PARSE_SIZE);
+
    if (param.ParamType == API_PARAM_TYPE_FILE) {
       file, _, err := request.FormFile(param.Name);
       if (err != nil) {
          if (param.Required) {
             method.log.Warn(fmt.Sprintf("Required file parameter not found: %s", param.Name));
-            return false, reflect.Value{};
+            return reflect.Value{}, ParamErrors{*param.missingError()};
          } else {
-            return true, reflect.ValueOf(File{nil});
+            return reflect.ValueOf(File{nil}), nil;
          }
       }
 
-      return true, reflect.ValueOf(File{&file});
+      return reflect.ValueOf(File{&file}), nil;
+   }
+
+   rawValues, err := method.fetchRawValues(param, request, body);
+   if (err != nil) {
+      method.log.WarnE(fmt.Sprintf("Unable to fetch parameter (%s)", param.Name), err);
+      return reflect.Value{}, body.badJSONErrors(param);
+   }
+
+   if (param.List) {
+      return method.fetchListParam(param, rawValues, argType);
    }
 
-   var stringValue string = strings.TrimSpace(request.FormValue(param.Name));
+   var stringValue string = "";
+   if (len(rawValues) > 0) {
+      stringValue = strings.TrimSpace(rawValues[0]);
+   }
+
+   // Empty values are the same as missing ones (unless explicitly allowed).
+   var missing bool = len(rawValues) == 0 || (stringValue == "" && !param.AllowEmpty);
+   if (missing && param.Default != nil) {
+      return toArgValue(param.Default, argType), nil;
+   }
 
-   if (param.Required && stringValue == "") {
+   if (missing && param.Required) {
       method.log.Warn(fmt.Sprintf("Required parameter not found: %s", param.Name));
-      return false, reflect.Value{};
+      return reflect.Value{}, ParamErrors{*param.missingError()};
    }
 
-   // If we are looking for string, then we are done.
-   if (param.ParamType == API_PARAM_TYPE_STRING) {
-      return true, reflect.ValueOf(stringValue);
+   // Missing non-required params get the zero value (which is nil for pointers).
+   if (missing) {
+      return reflect.Zero(argType), nil;
    }
 
-   // We must be looking for an int (only ints, files, and strings are allowed).
+   value, paramErr := method.parseParamValue(param, stringValue);
+   if (paramErr != nil) {
+      return reflect.Value{}, ParamErrors{*paramErr};
+   }
+
+   return toArgValue(value, argType), nil;
+}
+
+// Build the slice for a list param out of all of its raw values.
+// Every bad element gets its own error, named with the element's index (eg "ids[2]").
+func (method ApiMethod) fetchListParam(param ApiMethodParam, rawValues []string, argType reflect.Type) (reflect.Value, ParamErrors) {
+   var stringValues []string = make([]string, 0, len(rawValues));
+   for _, rawValue := range(rawValues) {
+      var parts []string = []string{rawValue};
+      if (param.SplitCommas) {
+         parts = strings.Split(rawValue, ",");
+      }
+
+      for _, part := range(parts) {
+         part = strings.TrimSpace(part);
+         if (part == "" && !param.AllowEmpty) {
+            continue;
+         }
 
-   // First check for an empty non-required int.
-   if (stringValue == "") {
-      return true, reflect.ValueOf(0);
+         stringValues = append(stringValues, part);
+      }
    }
 
-   intValue, err := strconv.Atoi(stringValue);
+   if (len(stringValues) == 0) {
+      if (param.Default != nil) {
+         // Copy the default so handlers cannot change it for later requests.
+         var defaultValue reflect.Value = reflect.ValueOf(param.Default);
+         return reflect.AppendSlice(reflect.MakeSlice(argType, 0, defaultValue.Len()), defaultValue), nil;
+      }
+
+      if (param.Required) {
+         method.log.Warn(fmt.Sprintf("Required parameter not found: %s", param.Name));
+         return reflect.Value{}, ParamErrors{*param.missingError()};
+      }
+
+      return reflect.Zero(argType), nil;
+   }
+
+   paramErr := param.checkCount(stringValues);
+   if (paramErr != nil) {
+      method.log.WarnE(fmt.Sprintf("Parameter (%s) failed a constraint", param.Name), paramErr);
+      return reflect.Value{}, ParamErrors{*paramErr};
+   }
+
+   var errs ParamErrors = nil;
+   var values reflect.Value = reflect.MakeSlice(argType, 0, len(stringValues));
+   for i, stringValue := range(stringValues) {
+      value, paramErr := method.parseParamValue(param, stringValue);
+      if (paramErr != nil) {
+         paramErr.Param = fmt.Sprintf("%s[%d]", param.Name, i);
+         errs = append(errs, *paramErr);
+         continue;
+      }
+
+      values = reflect.Append(values, reflect.ValueOf(value));
+   }
+
+   if (len(errs) > 0) {
+      return reflect.Value{}, errs;
+   }
+
+   return values, nil;
+}
+
+// Parse a single (trimmed, non-missing) value and check it against the param's constraints.
+func (method ApiMethod) parseParamValue(param ApiMethodParam, stringValue string) (interface{}, *ParamError) {
+   var info paramTypeInfo = paramTypes[param.ParamType];
+
+   value, err := info.parse(stringValue);
    if (err != nil) {
-      method.log.WarnE(fmt.Sprintf("Unable to convert int parameter (%s) from string: '%s'", param.Name, stringValue), err);
-      return false, reflect.ValueOf(0);
+      method.log.WarnE(fmt.Sprintf("Unable to convert %s parameter (%s) from string: '%s'", info.name, param.Name, stringValue), err);
+      return nil, &ParamError{
+         Param: param.Name,
+         Reason: PARAM_ERROR_BAD_TYPE,
+         Value: stringValue,
+         Message: fmt.Sprintf("must be a %s", info.name),
+      };
+   }
+
+   paramErr := param.checkConstraints(value);
+   if (paramErr != nil) {
+      method.log.WarnE(fmt.Sprintf("Parameter (%s) failed a constraint", param.Name), paramErr);
+      return nil, paramErr;
    }
 
-   return true, reflect.ValueOf(intValue);
+   return value, nil;
+}
+
+func (param ApiMethodParam) missingError() *ParamError {
+   return &ParamError{
+      Param: param.Name,
+      Reason: PARAM_ERROR_MISSING,
+      Message: "is required",
+   };
+}
+
+// The details of JSON errors are only logged (they may include internal type names).
+func (param ApiMethodParam) badJSONError() *ParamError {
+   return &ParamError{
+      Param: param.Name,
+      Reason: PARAM_ERROR_BAD_TYPE,
+      Message: "the request body must be valid JSON of the expected type",
+   };
+}
+
+// Convert a parsed value into the handler's argument type (which may be a pointer to the value's type).
+func toArgValue(value interface{}, argType reflect.Type) reflect.Value {
+   var rtn reflect.Value = reflect.ValueOf(value);
+   if (argType.Kind() != reflect.Pointer || rtn.Type() == argType) {
+      return rtn;
+   }
+
+   var pointer reflect.Value = reflect.New(rtn.Type());
+   pointer.Elem().Set(rtn);
+   return pointer;
+}
+
+// Get all the string values for a (non-file) param from the param's source.
+// An empty result means the param was not present at all (but it may be present with an empty value).
+// When the request has a JSON body, body params come from the top-level fields of the body.
+func (method ApiMethod) fetchRawValues(param ApiMethodParam, request *http.Request, body *jsonBody) ([]string, error) {
+   switch param.Source {
+   case API_PARAM_SOURCE_PATH:
+      var value string = method.pathValue(param.Name, request);
+      if (value == "") {
+         return nil, nil;
+      }
+
+      return []string{value}, nil;
+   case API_PARAM_SOURCE_QUERY:
+      return request.URL.Query()[param.Name], nil;
+   case API_PARAM_SOURCE_BODY:
+      if (isJSONRequest(request)) {
+         return jsonBodyValues(param, body);
+      }
+
+      return request.PostForm[param.Name], nil;
+   case API_PARAM_SOURCE_HEADER:
+      return request.Header.Values(param.Name), nil;
+   case API_PARAM_SOURCE_COOKIE:
+      var values []string = nil;
+      for _, cookie := range(request.Cookies()) {
+         if (cookie.Name == param.Name) {
+            values = append(values, cookie.Value);
+         }
+      }
+
+      return values, nil;
+   default:
+      var values []string = request.Form[param.Name];
+      if (len(values) == 0 && isJSONRequest(request)) {
+         return jsonBodyValues(param, body);
+      }
+
+      return values, nil;
+   }
+}
+
+// Only lists will split a JSON array into multiple values.
+func jsonBodyValues(param ApiMethodParam, body *jsonBody) ([]string, error) {
+   if (param.List) {
+      return body.fieldValues(param.Name);
+   }
+
+   value, ok, err := body.field(param.Name);
+   if (err != nil || !ok) {
+      return nil, err;
+   }
+
+   return []string{value}, nil;
+}
+
+// Get the value for a path placeholder.
+// Routers (and http.ServeMux) will have already matched the path,
+// but we can match it ourselves if the method was mounted some other way.
+func (method ApiMethod) pathValue(name string, request *http.Request) string {
+   var value string = request.PathValue(name);
+   if (value != "") {
+      return value;
+   }
+
+   values, ok := method.template.match(request.URL.Path);
+   if (!ok) {
+      return "";
+   }
+
+   return values[name];
 }
 
 // Send a response over |response|.
 // On error, |responseString| will be ignored.
-// In not supplied, the |httpStatus| will become http.StatusInternalServerError on error and
+// In not supplied, the |httpStatus| will become the APIError's status (or http.StatusInternalServerError) on error and
 // http.StatusOK on success.
-func (method ApiMethod) sendResponse(responseString string, err error, httpStatus int, response http.ResponseWriter) {
+func (method ApiMethod) sendResponse(responseString string, err error, httpStatus int, response http.ResponseWriter, request *http.Request) {
    if (err != nil) {
       method.log.ErrorE("API Error", err);
 
       if (httpStatus == 0) {
-         httpStatus = http.StatusInternalServerError;
+         httpStatus = errorStatus(err);
       }
 
+      var responseObj interface{} = buildErrorResponse(method.errorResponder, err, httpStatus, request);
+      response.Header().Set("Content-Type", errorContentType(responseObj, response.Header().Get("Content-Type")));
+
       // Any serialization errors will be ignored at this point.
-      responseString, _ = method.serializer(method.errorResponder(err, httpStatus));
+      responseString, _ = method.serializer(responseObj);
       response.WriteHeader(httpStatus);
       fmt.Fprintln(response, responseString);
    } else {
@@ -399,34 +1063,93 @@ func (method ApiMethod) sendResponse(responseString string, err error, httpStatu
    }
 }
 
+// Send a response built by the error responder without ever invoking the handler.
+func (method ApiMethod) sendErrorResponse(err error, httpStatus int, response http.ResponseWriter, request *http.Request) {
+   var responseObj interface{} = buildErrorResponse(method.errorResponder, err, httpStatus, request);
+
+   // Any serialization errors will be ignored at this point.
+   responseString, _ := method.serializer(responseObj);
+
+   response.Header().Set("Content-Type", errorContentType(responseObj, method.contentType));
+   response.WriteHeader(httpStatus);
+   fmt.Fprintln(response, responseString);
+}
+
+// The status for an error returned by a handler.
+// APIErrors (without a zero status) pick their own, everything else is a 500.
+func errorStatus(err error) int {
+   apiErr, ok := asAPIError(err);
+   if (ok && apiErr.Status != 0) {
+      return apiErr.Status;
+   }
+
+   return http.StatusInternalServerError;
+}
+
+// The return values of handleAPIRequest() for params that could not be passed to the handler.
+// Bodies that are too large (http.MaxBytesError) get a 413 instead.
+func (method ApiMethod) badRequestResponse(err error, request *http.Request) (interface{}, int, string, error) {
+   var httpStatus int = http.StatusBadRequest;
+
+   var maxBytesErr *http.MaxBytesError;
+   if (errors.As(err, &maxBytesErr)) {
+      httpStatus = http.StatusRequestEntityTooLarge;
+   }
+
+   var responseObj interface{} = buildErrorResponse(method.errorResponder, err, httpStatus, request);
+   return responseObj, httpStatus, errorContentType(responseObj, method.contentType), nil;
+}
+
 // Tries to authorize a request.
-// Returns: success, user id, user name, request token, and response object.
-// user id and token will only be populated on success.
-// response object will only be populated on error.
-func (method ApiMethod) authRequest(request *http.Request) (bool, int, string, string, interface{}) {
-   token, ok := getToken(request, method.allowTokenParam);
+// Returns: success, the request's principal, response object, and response status.
+// The principal will only be populated on success (and may be nil for anonymous requests if auth is optional).
+// The response object and status will only be populated on error.
+// 401s get a Basic auth challenge if there is a Basic auth validator or the request tried Basic auth.
+func (method ApiMethod) authRequest(response http.ResponseWriter, request *http.Request) (bool, *Principal, interface{}, int) {
+   credential, ok := extractCredential(request, method.getTokenExtractors());
+   if (!ok && method.optionalAuth && len(method.requiredRoles) == 0 && len(method.requiredScopes) == 0) {
+      return true, nil, nil, 0;
+   }
 
-   if (!ok) {
-      return false, 0, "", "", method.errorResponder(TokenValidationError{TOKEN_VALIDATION_NO_TOKEN}, http.StatusUnauthorized);
+   principal, responseObj, httpStatus := method.authCredential(credential, ok, request);
+   if (httpStatus == http.StatusUnauthorized && (method.basicAuthValidator != nil || credential.Source == TOKEN_SOURCE_BASIC)) {
+      var realm string = method.basicAuthRealm;
+      if (realm == "") {
+         realm = DEFAULT_BASIC_AUTH_REALM;
+      }
+
+      response.Header().Set("WWW-Authenticate", basicAuthChallenge(realm));
    }
 
-   // Check for empty tokens.
-   if (strings.TrimSpace(token) == "") {
-      return false, 0, "", "", method.errorResponder(TokenValidationError{TOKEN_VALIDATION_NO_TOKEN}, http.StatusUnauthorized);
+   return httpStatus == 0, principal, responseObj, httpStatus;
+}
+
+// Validate and authorize a credential (|found| is false if the request did not have one).
+// Returns a zero status on success.
+func (method ApiMethod) authCredential(credential Credential, found bool, request *http.Request) (*Principal, interface{}, int) {
+   if (!found) {
+      return nil, buildErrorResponse(method.errorResponder, TokenValidationError{TOKEN_VALIDATION_NO_TOKEN}, http.StatusUnauthorized, request), http.StatusUnauthorized;
    }
 
-   userId, userName, err := method.tokenValidator(token, method.log);
+   principal, err := method.validateCredential(credential);
    if (err != nil) {
       validationErr, ok := err.(TokenValidationError);
       if (!ok) {
          // Some other (non-validation) error.
-         return false, 0, "", "", method.errorResponder(nil, http.StatusInternalServerError);
+         method.log.ErrorE("Failed to validate a token", err);
+         return nil, buildErrorResponse(method.errorResponder, nil, http.StatusInternalServerError, request), http.StatusInternalServerError;
       }
 
-      return false, 0, "", "", method.errorResponder(validationErr, http.StatusUnauthorized);
+      return nil, buildErrorResponse(method.errorResponder, validationErr, http.StatusUnauthorized, request), http.StatusUnauthorized;
+   }
+
+   authErr, ok := method.authorize(principal);
+   if (!ok) {
+      method.log.Debug(fmt.Sprintf("User (%d) is not authorized for API handler (%s): %v", principal.UserId, method.path, authErr));
+      return nil, buildErrorResponse(method.errorResponder, authErr, http.StatusForbidden, request), http.StatusForbidden;
    }
 
-   return true, userId, userName, token, nil;
+   return principal, nil, 0;
 }
 
 func (method ApiMethod) String() string {
@@ -435,6 +1158,10 @@ func (method ApiMethod) String() string {
    rtn += fmt.Sprintf("%s\n", method.path);
    rtn += fmt.Sprintf("   Authentication Required: %v\n", method.auth);
 
+   if (method.allowedMethods != nil) {
+      rtn += fmt.Sprintf("   HTTP Methods: %s\n", method.allowHeader());
+   }
+
    if (len(method.params) == 0) {
       rtn += "   Params: None\n"
    } else {
@@ -448,18 +1175,46 @@ func (method ApiMethod) String() string {
    return rtn;
 }
 
+// The type of the param's value (and Default).
+// Lists are slices of the param type's Go type.
+func (param ApiMethodParam) valueType() reflect.Type {
+   var goType reflect.Type = paramTypes[param.ParamType].goType;
+   if (param.List && goType != nil) {
+      return reflect.SliceOf(goType);
+   }
+
+   return goType;
+}
+
 func (param ApiMethodParam) String() string {
-   var typeString string = "int";
-   if (param.ParamType == API_PARAM_TYPE_STRING) {
-      typeString = "string";
-   } else if (param.ParamType == API_PARAM_TYPE_FILE) {
-      typeString = "File";
+   var typeString string = paramTypes[param.ParamType].name;
+   if (param.List) {
+      typeString = "[]" + typeString;
    }
 
-   var requiredString string = "";
+   var flags []string = make([]string, 0);
    if (param.Required) {
-      requiredString = " (required)";
+      flags = append(flags, "required");
+   }
+
+   if (param.AllowEmpty) {
+      flags = append(flags, "allow empty");
+   }
+
+   var requiredString string = "";
+   if (len(flags) > 0) {
+      requiredString = fmt.Sprintf(" (%s)", strings.Join(flags, ", "));
+   }
+
+   var sourceString string = "";
+   if (param.Source != API_PARAM_SOURCE_ANY) {
+      sourceString = fmt.Sprintf(" [%s]", paramSourceNames[param.Source]);
+   }
+
+   var defaultString string = "";
+   if (param.Default != nil) {
+      defaultString = fmt.Sprintf(" (default: %s)", formatParamValue(param.Default));
    }
 
-   return fmt.Sprintf("%s %s%s", param.Name, typeString, requiredString);
+   return fmt.Sprintf("%s %s%s%s%s", param.Name, typeString, requiredString, defaultString, sourceString);
 }
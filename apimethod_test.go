@@ -1,9 +1,15 @@
 package goapi;
 
 import (
+   "context"
+   "errors"
    "fmt"
    "net/http"
+   "net/http/httptest"
+   "net/url"
+   "strings"
    "testing"
+   "time"
 )
 
 type TestInfo struct {
@@ -36,9 +42,9 @@ func ExampleApiMethod_String_params() {
       handler_intStringFile,
       false,
       []ApiMethodParam{
-         ApiMethodParam{"someInt", API_PARAM_TYPE_INT, true},
-         ApiMethodParam{"someString", API_PARAM_TYPE_STRING, false},
-         ApiMethodParam{"someFile", API_PARAM_TYPE_FILE, false},
+         ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Required: true},
+         ApiMethodParam{Name: "someString", ParamType: API_PARAM_TYPE_STRING, Required: false},
+         ApiMethodParam{Name: "someFile", ParamType: API_PARAM_TYPE_FILE, Required: false},
       },
    );
    fmt.Println(method);
@@ -53,6 +59,259 @@ func ExampleApiMethod_String_params() {
    //    Handler: github.com/eriq-augustine/goapi.handler_intStringFile
 }
 
+func ExampleApiMethod_String_defaults() {
+   factory := ApiMethodFactory{};
+   method := factory.NewApiMethod(
+      "/list",
+      handler_defaults,
+      false,
+      []ApiMethodParam{
+         ApiMethodParam{Name: "pageSize", ParamType: API_PARAM_TYPE_INT, Default: 50, Source: API_PARAM_SOURCE_QUERY},
+         ApiMethodParam{Name: "since", ParamType: API_PARAM_TYPE_TIME, Default: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+      },
+   );
+   fmt.Println(method);
+
+   // Output:
+   // /list
+   //    Authentication Required: false
+   //    Params:
+   //       pageSize int (default: 50) [query]
+   //       since time.Time (default: 2020-01-02T03:04:05Z)
+   //    Handler: github.com/eriq-augustine/goapi.handler_defaults
+}
+
+func TestDefaultParams(t *testing.T) {
+   factory := ApiMethodFactory{};
+   method := factory.NewApiMethod("/list", func(pageSize *int, since time.Time) interface{} {
+      return fmt.Sprintf("%d %d", *pageSize, since.Unix());
+   }, false, []ApiMethodParam{
+      ApiMethodParam{Name: "pageSize", ParamType: API_PARAM_TYPE_INT, Default: 50},
+      ApiMethodParam{Name: "since", ParamType: API_PARAM_TYPE_TIME, Default: time.Unix(100, 0)},
+   });
+
+   tests := []struct{
+      title string
+      query string
+      response string
+   } {
+      {"Defaults", "", `"50 100"`},
+      {"Empty", "pageSize=&since=", `"50 100"`},
+      {"Values", "pageSize=0&since=1970-01-01T00:00:05Z", `"0 5"`},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodGet, "/list?" + test.query, nil);
+      response := httptest.NewRecorder();
+      method.Middleware()(response, request);
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
+func TestListParams(t *testing.T) {
+   factory := ApiMethodFactory{};
+   factory.SetGeneralErrorResponser(ruleErrorResponder);
+
+   var tags ApiMethodParam = ApiMethodParam{Name: "tag", ParamType: API_PARAM_TYPE_STRING, List: true};
+   var splitTags ApiMethodParam = ApiMethodParam{Name: "tag", ParamType: API_PARAM_TYPE_STRING, List: true, SplitCommas: true};
+   var ids ApiMethodParam = ApiMethodParam{Name: "id", ParamType: API_PARAM_TYPE_INT, List: true, SplitCommas: true, MinCount: 2, MaxCount: 3, Min: 1};
+   var defaultIds ApiMethodParam = ApiMethodParam{Name: "id", ParamType: API_PARAM_TYPE_INT, List: true, Default: []int{1, 2}};
+
+   tests := []struct{
+      title string
+      param ApiMethodParam
+      handler interface{}
+      query string
+      body string
+      response string
+   } {
+      {"Repeated", tags, handler_echoStrings, "tag=a&tag=b", "", `["a","b"]`},
+      {"Single", tags, handler_echoStrings, "tag=a", "", `["a"]`},
+      {"Missing", tags, handler_echoStrings, "", "", `null`},
+      {"Empty Values", tags, handler_echoStrings, "tag=&tag=a&tag=", "", `["a"]`},
+      {"No Split", tags, handler_echoStrings, "tag=a,b", "", `["a,b"]`},
+      {"Split", splitTags, handler_echoStrings, "tag=a,b&tag=c", "", `["a","b","c"]`},
+      {"JSON Array", tags, handler_echoStrings, "", `{"tag": ["a", "b"]}`, `["a","b"]`},
+      {"JSON Single", tags, handler_echoStrings, "", `{"tag": "a"}`, `["a"]`},
+      {"Ints", ids, handler_echoInts, "id=1,2&id=3", "", `[1,2,3]`},
+      {"JSON Ints", ids, handler_echoInts, "", `{"id": [1, 2]}`, `[1,2]`},
+      {"Min Count", ids, handler_echoInts, "id=1", "", `"minCount"`},
+      {"Max Count", ids, handler_echoInts, "id=1,2,3,4", "", `"maxCount"`},
+      {"Element Constraint", ids, handler_echoInts, "id=1,0", "", `"min"`},
+      {"Bad Element", ids, handler_echoInts, "id=1,a", "", `{"Success":false,"Code":400,"Params":[{"Name":"id[1]","Code":"bad-type","Message":"must be a int"}]}`},
+      {"Default", defaultIds, handler_echoInts, "", "", `[1,2]`},
+      {"Default Override", defaultIds, handler_echoInts, "id=3", "", `[3]`},
+   };
+
+   for _, test := range(tests) {
+      method := factory.NewApiMethod("/list", test.handler, false, []ApiMethodParam{test.param});
+
+      var request *http.Request;
+      if (test.body == "") {
+         request = httptest.NewRequest(http.MethodGet, "/list?" + test.query, nil);
+      } else {
+         request = httptest.NewRequest(http.MethodPost, "/list?" + test.query, strings.NewReader(test.body));
+         request.Header.Set("Content-Type", "application/json");
+      }
+
+      response := httptest.NewRecorder();
+      method.Middleware()(response, request);
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
+func TestParamErrors(t *testing.T) {
+   factory := ApiMethodFactory{};
+   factory.SetGeneralErrorResponser(func(err error, httpStatus int) interface{} {
+      var paramErrs ParamErrors;
+      if (!errors.As(err, &paramErrs)) {
+         return "not param errors";
+      }
+
+      var rtn []string = make([]string, 0);
+      for _, paramErr := range(paramErrs) {
+         rtn = append(rtn, fmt.Sprintf("%s:%d:%s:%s", paramErr.Param, paramErr.Reason, paramErr.Rule, paramErr.Value));
+      }
+
+      return rtn;
+   });
+
+   method := factory.NewApiMethod("/errors", func(count int, name string, size *int, ids []int) {}, false, []ApiMethodParam{
+      ApiMethodParam{Name: "count", ParamType: API_PARAM_TYPE_INT, Required: true},
+      ApiMethodParam{Name: "name", ParamType: API_PARAM_TYPE_STRING, Required: true, MaxLength: 3},
+      ApiMethodParam{Name: "size", ParamType: API_PARAM_TYPE_INT, Min: 0},
+      ApiMethodParam{Name: "id", ParamType: API_PARAM_TYPE_INT, List: true, MaxCount: 3},
+   });
+
+   body := factory.NewApiMethod("/body", handler_echoIntString, false, []ApiMethodParam{
+      ApiMethodParam{Name: "count", ParamType: API_PARAM_TYPE_INT, Required: true, Source: API_PARAM_SOURCE_BODY},
+      ApiMethodParam{Name: "name", ParamType: API_PARAM_TYPE_STRING, Required: true, Source: API_PARAM_SOURCE_BODY},
+   });
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+      query string
+      body string
+      response string
+   } {
+      {"All", method, "name=abcd&size=-1&id=1&id=2&id=3&id=4", "", `["count:0::","name:2:maxLength:abcd","size:2:min:-1","id:2:maxCount:[1, 2, 3, 4]"]`},
+      {"Bad Type", method, "count=a&name=abc&id=b", "", `["count:1::a","id[0]:1::b"]`},
+      {"Bad Elements", method, "count=1&name=abc&id=a&id=2&id=c", "", `["id[0]:1::a","id[2]:1::c"]`},
+      {"One", method, "count=1&name=abc&size=a", "", `["size:1::a"]`},
+      {"None", method, "count=1&name=abc", "", `null`},
+      {"Bad JSON Once", body, "", `{"count": 5`, `["count:1::"]`},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodPost, "/errors?" + test.query, strings.NewReader(test.body));
+      request.Header.Set("Content-Type", "application/json");
+
+      response := httptest.NewRecorder();
+      test.method.Middleware()(response, request);
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+
+   // The default responder tells the client which params failed (but not the values).
+   defaultFactory := ApiMethodFactory{};
+   defaultMethod := defaultFactory.NewApiMethod("/errors", func(count int, ids []int) {}, false, []ApiMethodParam{
+      ApiMethodParam{Name: "count", ParamType: API_PARAM_TYPE_INT, Required: true, Min: 1},
+      ApiMethodParam{Name: "id", ParamType: API_PARAM_TYPE_INT, List: true},
+   });
+
+   request := httptest.NewRequest(http.MethodGet, "/errors?count=0&id=secret", nil);
+   response := httptest.NewRecorder();
+   defaultMethod.Middleware()(response, request);
+
+   var expected string = `{"Success":false,"Code":400,"Params":[{"Name":"count","Code":"min","Message":"must be at least 1"},{"Name":"id[0]","Code":"bad-type","Message":"must be a int"}]}`;
+   if (strings.TrimSpace(response.Body.String()) != expected) {
+      failTest(t, "Default Responder", expected, response.Body.String());
+   }
+}
+
+func TestAPIError(t *testing.T) {
+   factory := ApiMethodFactory{};
+
+   var notFound APIError = APIError{Status: http.StatusNotFound, Code: "not_found", Message: "No such item", Cause: fmt.Errorf("secret database details")};
+
+   tests := []struct{
+      title string
+      handler interface{}
+      status int
+      response string
+   } {
+      {"Basic", func() error { return notFound; }, http.StatusNotFound, `{"Success":false,"Code":404,"ErrorCode":"not_found","Message":"No such item"}`},
+      {"Pointer", func() error { return &notFound; }, http.StatusNotFound, `{"Success":false,"Code":404,"ErrorCode":"not_found","Message":"No such item"}`},
+      {"Wrapped", func() error { return fmt.Errorf("wrapped: %w", notFound); }, http.StatusNotFound, `{"Success":false,"Code":404,"ErrorCode":"not_found","Message":"No such item"}`},
+      {"Explicit Status", func() (int, error) { return http.StatusConflict, notFound; }, http.StatusConflict, `{"Success":false,"Code":409,"ErrorCode":"not_found","Message":"No such item"}`},
+      {"No Status", func() error { return APIError{Code: "oops"}; }, http.StatusInternalServerError, `{"Success":false,"Code":500,"ErrorCode":"oops"}`},
+      {"Plain Error", func() error { return fmt.Errorf("secret"); }, http.StatusInternalServerError, `{"Success":false,"Code":500}`},
+   };
+
+   for _, test := range(tests) {
+      method := factory.NewApiMethod("/error", test.handler, false, []ApiMethodParam{});
+
+      request := httptest.NewRequest(http.MethodGet, "/error", nil);
+      response := httptest.NewRecorder();
+      method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title + " (status)", test.status, response.Code);
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
+func TestConstraints(t *testing.T) {
+   factory := ApiMethodFactory{};
+   factory.SetGeneralErrorResponser(ruleErrorResponder);
+
+   tests := []struct{
+      title string
+      param ApiMethodParam
+      handler interface{}
+      value string
+      response string
+   } {
+      {"Min", ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_INT, Min: 1, Max: 10}, handler_echoInt, "0", `"min"`},
+      {"Max", ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_INT, Min: 1, Max: 10}, handler_echoInt, "11", `"max"`},
+      {"Min Max Ok", ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_INT, Min: 1, Max: 10}, handler_echoInt, "10", `10`},
+      {"Duration Max", ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_DURATION, Max: time.Hour}, func(val time.Duration) {}, "61m", `"max"`},
+      {"Min Length", ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_STRING, MinLength: 2, MaxLength: 3}, handler_echoString, "é", `"minLength"`},
+      {"Max Length", ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_STRING, MinLength: 2, MaxLength: 3}, handler_echoString, "abcd", `"maxLength"`},
+      {"Length Ok", ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_STRING, MinLength: 2, MaxLength: 3}, handler_echoString, "éé", `"éé"`},
+      {"Pattern", ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_STRING, Pattern: "^[a-z]+$"}, handler_echoString, "abc1", `"pattern"`},
+      {"Pattern Ok", ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_STRING, Pattern: "^[a-z]+$"}, handler_echoString, "abc", `"abc"`},
+      {"Enum", ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_STRING, Enum: []string{"asc", "desc"}}, handler_echoString, "up", `"enum"`},
+      {"Enum Ok", ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_STRING, Enum: []string{"asc", "desc"}}, handler_echoString, "desc", `"desc"`},
+      {"Enum Int Ok", ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_INT, Enum: []string{"1", "2"}}, handler_echoInt, "02", `2`},
+   };
+
+   for _, test := range(tests) {
+      method := factory.NewApiMethod("/constraints", test.handler, false, []ApiMethodParam{test.param});
+
+      request := httptest.NewRequest(http.MethodGet, "/constraints?val=" + url.QueryEscape(test.value), nil);
+      response := httptest.NewRecorder();
+      method.Middleware()(response, request);
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
 func TestValidation(t *testing.T) {
    tests := []TestInfo{
       {
@@ -101,7 +360,7 @@ func TestValidation(t *testing.T) {
          handler: handler_string,
          auth: false,
          params: []ApiMethodParam{
-            ApiMethodParam{"someString", API_PARAM_TYPE_STRING, true},
+            ApiMethodParam{Name: "someString", ParamType: API_PARAM_TYPE_STRING, Required: true},
          },
          valid: true,
       },
@@ -111,7 +370,7 @@ func TestValidation(t *testing.T) {
          handler: handler_int,
          auth: false,
          params: []ApiMethodParam{
-            ApiMethodParam{"someInt", API_PARAM_TYPE_INT, true},
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Required: true},
          },
          valid: true,
       },
@@ -121,7 +380,7 @@ func TestValidation(t *testing.T) {
          handler: handler_file,
          auth: false,
          params: []ApiMethodParam{
-            ApiMethodParam{"someFile", API_PARAM_TYPE_FILE, true},
+            ApiMethodParam{Name: "someFile", ParamType: API_PARAM_TYPE_FILE, Required: true},
          },
          valid: true,
       },
@@ -131,10 +390,10 @@ func TestValidation(t *testing.T) {
          handler: handler_multipleIntString,
          auth: false,
          params: []ApiMethodParam{
-            ApiMethodParam{"someString1", API_PARAM_TYPE_STRING, true},
-            ApiMethodParam{"someInt1", API_PARAM_TYPE_INT, true},
-            ApiMethodParam{"someString2", API_PARAM_TYPE_STRING, true},
-            ApiMethodParam{"someInt2", API_PARAM_TYPE_INT, true},
+            ApiMethodParam{Name: "someInt1", ParamType: API_PARAM_TYPE_INT, Required: true},
+            ApiMethodParam{Name: "someString1", ParamType: API_PARAM_TYPE_STRING, Required: true},
+            ApiMethodParam{Name: "someInt2", ParamType: API_PARAM_TYPE_INT, Required: true},
+            ApiMethodParam{Name: "someString2", ParamType: API_PARAM_TYPE_STRING, Required: true},
          },
          valid: true,
       },
@@ -144,10 +403,25 @@ func TestValidation(t *testing.T) {
          handler: handler_all,
          auth: true,
          params: []ApiMethodParam{
-            ApiMethodParam{"someString1", API_PARAM_TYPE_STRING, true},
-            ApiMethodParam{"someInt1", API_PARAM_TYPE_INT, true},
-            ApiMethodParam{"someString2", API_PARAM_TYPE_STRING, true},
-            ApiMethodParam{"someInt2", API_PARAM_TYPE_INT, true},
+            ApiMethodParam{Name: "someInt1", ParamType: API_PARAM_TYPE_INT, Required: true},
+            ApiMethodParam{Name: "someString1", ParamType: API_PARAM_TYPE_STRING, Required: true},
+            ApiMethodParam{Name: "someInt2", ParamType: API_PARAM_TYPE_INT, Required: true},
+            ApiMethodParam{Name: "someString2", ParamType: API_PARAM_TYPE_STRING, Required: true},
+         },
+         valid: true,
+      },
+      {
+         title: "Valid - Params Scalar Types",
+         path: "/good/path",
+         handler: handler_scalars,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someBool", ParamType: API_PARAM_TYPE_BOOL, Required: true},
+            ApiMethodParam{Name: "someFloat", ParamType: API_PARAM_TYPE_FLOAT, Required: true},
+            ApiMethodParam{Name: "someInt64", ParamType: API_PARAM_TYPE_INT64, Required: true},
+            ApiMethodParam{Name: "someUint", ParamType: API_PARAM_TYPE_UINT, Required: true},
+            ApiMethodParam{Name: "someTime", ParamType: API_PARAM_TYPE_TIME, Required: true},
+            ApiMethodParam{Name: "someDuration", ParamType: API_PARAM_TYPE_DURATION, Required: true},
          },
          valid: true,
       },
@@ -184,6 +458,27 @@ func TestValidation(t *testing.T) {
          valid: true,
       },
 
+      {
+         title: "Valid - Path Params",
+         path: "/users/{someInt}/files/{someString}",
+         handler: handler_intString,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Required: true, Source: API_PARAM_SOURCE_PATH},
+            ApiMethodParam{Name: "someString", ParamType: API_PARAM_TYPE_STRING, Required: true, Source: API_PARAM_SOURCE_PATH},
+         },
+         valid: true,
+      },
+
+      {
+         title: "Valid - Params Struct",
+         path: "/users/{id}",
+         handler: handler_paramsStruct,
+         auth: true,
+         params: nil,
+         valid: true,
+      },
+
       // Invalid methods
 
       {
@@ -216,7 +511,7 @@ func TestValidation(t *testing.T) {
          handler: handler_empty,
          auth: false,
          params: []ApiMethodParam{
-            ApiMethodParam{"someString", API_PARAM_TYPE_STRING, true},
+            ApiMethodParam{Name: "someString", ParamType: API_PARAM_TYPE_STRING, Required: true},
          },
          valid: false,
       },
@@ -250,7 +545,7 @@ func TestValidation(t *testing.T) {
          handler: handler_empty,
          auth: false,
          params: []ApiMethodParam{
-            ApiMethodParam{"", API_PARAM_TYPE_STRING, true},
+            ApiMethodParam{Name: "", ParamType: API_PARAM_TYPE_STRING, Required: true},
          },
          valid: false,
       },
@@ -260,7 +555,7 @@ func TestValidation(t *testing.T) {
          handler: handler_int,
          auth: false,
          params: []ApiMethodParam{
-            ApiMethodParam{"someInt", 99, true},
+            ApiMethodParam{Name: "someInt", ParamType: 99, Required: true},
          },
          valid: false,
       },
@@ -270,80 +565,877 @@ func TestValidation(t *testing.T) {
          handler: handler_int,
          auth: false,
          params: []ApiMethodParam{
-            ApiMethodParam{"someInt", API_PARAM_TYPE_INT, true},
-            ApiMethodParam{"someInt", API_PARAM_TYPE_INT, true},
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Required: true},
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Required: true},
          },
          valid: false,
       },
       {
-         title: "Inalid - Return 4",
-         path: "/good/path",
-         handler: handler_return4,
+         title: "Inalid - Placeholder Without Param",
+         path: "/users/{someInt}",
+         handler: handler_empty,
          auth: false,
          params: []ApiMethodParam{},
          valid: false,
       },
       {
-         title: "Inalid - Return Bad Types",
+         title: "Inalid - Placeholder Non-Path Param",
+         path: "/users/{someInt}",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Required: true},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Path Param Without Placeholder",
+         path: "/users",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Required: true, Source: API_PARAM_SOURCE_PATH},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Path File Param",
+         path: "/users/{someFile}",
+         handler: handler_file,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someFile", ParamType: API_PARAM_TYPE_FILE, Required: true, Source: API_PARAM_SOURCE_PATH},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Partial Placeholder",
+         path: "/users/id{someInt}",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Required: true, Source: API_PARAM_SOURCE_PATH},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Header File Param",
          path: "/good/path",
-         handler: handler_returnBadType,
+         handler: handler_file,
          auth: false,
-         params: []ApiMethodParam{},
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someFile", ParamType: API_PARAM_TYPE_FILE, Required: true, Source: API_PARAM_SOURCE_HEADER},
+         },
          valid: false,
       },
-   };
-
-   factory := ApiMethodFactory{};
-   factory.SetTokenValidator(fakeValidateToken);
-   for _, test := range(tests) {
-      validationTest(t, factory, test);
-   }
-}
-
-func validationTest(t *testing.T, factory ApiMethodFactory, info TestInfo) {
-   defer func() {
-      // Check panic status.
-      r := recover();
-
-      if (r != nil && info.valid) {
-         // Too panicy.
-         t.Errorf("%s: Invalid Panic", info.title)
-      } else if (r == nil && !info.valid) {
-         // Not panicy enough.
-         t.Errorf("%s: Failed to Panic", info.title)
-      }
-   }();
-
-   factory.NewApiMethod(info.path, info.handler, info.auth, info.params);
-}
-
-func fakeValidateToken(token string, log Logger) (userId int, userName string, err error) {
-   return 0, "", nil;
-}
-
-// Test handlers.
-
-func handler_empty() {}
-
-func handler_request(request *http.Request) {}
-
-func handler_response(response http.ResponseWriter) {}
-
-func handler_implicits(id UserId, name UserName, token Token) {}
-
-func handler_userId(id UserId) {}
-
-func handler_userName(name UserName) {}
-
-func handler_token(token Token) {}
-
-func handler_string(someString string) {}
-
-func handler_int(someInt int) {}
-
-func handler_file(someFile File) {}
-
-func handler_intString(someInt int, someString string) {}
+      {
+         title: "Inalid - Params Bad Source",
+         path: "/good/path",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Required: true, Source: 99},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Header JSON Param",
+         path: "/good/path",
+         handler: handler_echoJSON,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "body", ParamType: API_PARAM_TYPE_JSON, Required: true, Source: API_PARAM_SOURCE_HEADER},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Params Struct And Params",
+         path: "/users/{id}",
+         handler: handler_paramsStruct,
+         auth: true,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "id", ParamType: API_PARAM_TYPE_INT, Required: true, Source: API_PARAM_SOURCE_PATH},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Params Struct Missing Placeholder",
+         path: "/users",
+         handler: handler_paramsStruct,
+         auth: true,
+         params: nil,
+         valid: false,
+      },
+      {
+         title: "Inalid - Params Struct Bad Source",
+         path: "/good/path",
+         handler: func(args struct{ Val int `goapi:"val,source=nowhere"` }) {},
+         auth: false,
+         params: nil,
+         valid: false,
+      },
+      {
+         title: "Inalid - Params Struct Bad Option",
+         path: "/good/path",
+         handler: func(args struct{ Val int `goapi:"val,optional"` }) {},
+         auth: false,
+         params: nil,
+         valid: false,
+      },
+      {
+         title: "Inalid - Params Struct Bad Type",
+         path: "/good/path",
+         handler: func(args struct{ Val float32 `goapi:"val"` }) {},
+         auth: false,
+         params: nil,
+         valid: false,
+      },
+      {
+         title: "Inalid - Params Struct Bad Default",
+         path: "/good/path",
+         handler: func(args struct{ Val int `goapi:"val,default=abc"` }) {},
+         auth: false,
+         params: nil,
+         valid: false,
+      },
+      {
+         title: "Inalid - Params Struct Required Default",
+         path: "/good/path",
+         handler: func(args struct{ Val int `goapi:"val,required,default=5"` }) {},
+         auth: false,
+         params: nil,
+         valid: false,
+      },
+      {
+         title: "Inalid - Params Struct Unexported",
+         path: "/good/path",
+         handler: func(args struct{ val int `goapi:"val"` }) {},
+         auth: false,
+         params: nil,
+         valid: false,
+      },
+      {
+         title: "Inalid - Params Out Of Order",
+         path: "/good/path",
+         handler: handler_intString,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someString", ParamType: API_PARAM_TYPE_STRING, Required: true},
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Required: true},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Params Inexact Type",
+         path: "/good/path",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT64, Required: true},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Allow Empty Int",
+         path: "/good/path",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, AllowEmpty: true},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Pointer File",
+         path: "/good/path",
+         handler: func(someFile *File) {},
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someFile", ParamType: API_PARAM_TYPE_FILE},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Default Wrong Type",
+         path: "/good/path",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Default: int64(5)},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Default Required",
+         path: "/good/path",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Required: true, Default: 5},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Default File",
+         path: "/good/path",
+         handler: handler_file,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someFile", ParamType: API_PARAM_TYPE_FILE, Default: File{}},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Min Greater Than Max",
+         path: "/good/path",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Min: 5, Max: 1},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Min Wrong Type",
+         path: "/good/path",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Min: 5.0},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Length On Int",
+         path: "/good/path",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, MaxLength: 5},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Bad Pattern",
+         path: "/good/path",
+         handler: handler_string,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someString", ParamType: API_PARAM_TYPE_STRING, Pattern: "[a-"},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Bad Enum",
+         path: "/good/path",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Enum: []string{"1", "two"}},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Default Violates Constraint",
+         path: "/good/path",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Default: 0, Min: 1},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Params Struct Bad Min",
+         path: "/good/path",
+         handler: func(args struct{ Val int `goapi:"val,min=a"` }) {},
+         auth: false,
+         params: nil,
+         valid: false,
+      },
+      {
+         title: "Valid - List",
+         path: "/good/path",
+         handler: handler_echoInts,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInts", ParamType: API_PARAM_TYPE_INT, List: true, SplitCommas: true, MinCount: 1, MaxCount: 2, Default: []int{1}},
+         },
+         valid: true,
+      },
+      {
+         title: "Valid - List Params Struct",
+         path: "/good/path",
+         handler: func(args struct{ Ids []int `goapi:"id,splitcommas,mincount=1,default=1|2"` }) {},
+         auth: false,
+         params: nil,
+         valid: true,
+      },
+      {
+         title: "Inalid - List Wrong Type",
+         path: "/good/path",
+         handler: handler_echoStrings,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInts", ParamType: API_PARAM_TYPE_INT, List: true},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - List Not Slice",
+         path: "/good/path",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInts", ParamType: API_PARAM_TYPE_INT, List: true},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - List Pointer",
+         path: "/good/path",
+         handler: func(someInts *[]int) {},
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInts", ParamType: API_PARAM_TYPE_INT, List: true},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - List Of Files",
+         path: "/good/path",
+         handler: func(files []File) {},
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "files", ParamType: API_PARAM_TYPE_FILE, List: true},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Split Commas Without List",
+         path: "/good/path",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, SplitCommas: true},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Count Without List",
+         path: "/good/path",
+         handler: handler_int,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, MinCount: 1},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Min Count Greater Than Max Count",
+         path: "/good/path",
+         handler: handler_echoInts,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInts", ParamType: API_PARAM_TYPE_INT, List: true, MinCount: 3, MaxCount: 2},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - List Default Not Slice",
+         path: "/good/path",
+         handler: handler_echoInts,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInts", ParamType: API_PARAM_TYPE_INT, List: true, Default: 1},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - List Default Violates Count",
+         path: "/good/path",
+         handler: handler_echoInts,
+         auth: false,
+         params: []ApiMethodParam{
+            ApiMethodParam{Name: "someInts", ParamType: API_PARAM_TYPE_INT, List: true, MaxCount: 1, Default: []int{1, 2}},
+         },
+         valid: false,
+      },
+      {
+         title: "Inalid - Return 4",
+         path: "/good/path",
+         handler: handler_return4,
+         auth: false,
+         params: []ApiMethodParam{},
+         valid: false,
+      },
+      {
+         title: "Inalid - Return Bad Types",
+         path: "/good/path",
+         handler: handler_returnBadType,
+         auth: false,
+         params: []ApiMethodParam{},
+         valid: false,
+      },
+   };
+
+   factory := ApiMethodFactory{};
+   factory.SetTokenValidator(fakeValidateToken);
+   for _, test := range(tests) {
+      validationTest(t, factory, test);
+   }
+}
+
+func TestAllowedMethods(t *testing.T) {
+   factory := ApiMethodFactory{};
+   method := factory.NewApiMethod("/delete", handler_empty, false, []ApiMethodParam{}).SetAllowedMethods("post", "DELETE");
+
+   tests := []struct{
+      httpMethod string
+      status int
+   } {
+      {http.MethodPost, http.StatusOK},
+      {http.MethodDelete, http.StatusOK},
+      {http.MethodOptions, http.StatusOK},
+      {http.MethodGet, http.StatusMethodNotAllowed},
+      {http.MethodHead, http.StatusMethodNotAllowed},
+      {http.MethodPut, http.StatusMethodNotAllowed},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(test.httpMethod, "/delete", nil);
+      response := httptest.NewRecorder();
+      method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.httpMethod, test.status, response.Code);
+         continue;
+      }
+
+      if (test.status == http.StatusMethodNotAllowed || test.httpMethod == http.MethodOptions) {
+         if (response.Header().Get("Allow") != "POST, DELETE, OPTIONS") {
+            failTest(t, test.httpMethod + " Allow", "POST, DELETE, OPTIONS", response.Header().Get("Allow"));
+         }
+
+         if (response.Header().Get("Access-Control-Allow-Methods") != "POST, DELETE, OPTIONS") {
+            failTest(t, test.httpMethod + " CORS", "POST, DELETE, OPTIONS", response.Header().Get("Access-Control-Allow-Methods"));
+         }
+      }
+   }
+
+   method.SetAllowedMethods(http.MethodGet);
+   if (method.allowHeader() != "GET, HEAD, OPTIONS") {
+      failTest(t, "Implicit HEAD", "GET, HEAD, OPTIONS", method.allowHeader());
+   }
+
+   func() {
+      defer func() {
+         if (recover() == nil) {
+            t.Errorf("Only OPTIONS: Failed to Panic");
+         }
+      }();
+
+      method.SetAllowedMethods(http.MethodOptions);
+   }();
+}
+
+func TestParamSources(t *testing.T) {
+   factory := ApiMethodFactory{};
+
+   tests := []struct{
+      title string
+      source int
+      query string
+      body string
+      header string
+      cookie string
+      status int
+      response string
+   } {
+      {"Any Query", API_PARAM_SOURCE_ANY, "val=query", "", "", "", http.StatusOK, `"query"`},
+      {"Any Body", API_PARAM_SOURCE_ANY, "", "val=body", "", "", http.StatusOK, `"body"`},
+      {"Query", API_PARAM_SOURCE_QUERY, "val=query", "val=body", "", "", http.StatusOK, `"query"`},
+      {"Query Missing", API_PARAM_SOURCE_QUERY, "", "val=body", "", "", http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"val","Code":"missing","Message":"is required"}]}`},
+      {"Body", API_PARAM_SOURCE_BODY, "val=query", "val=body", "", "", http.StatusOK, `"body"`},
+      {"Body Missing", API_PARAM_SOURCE_BODY, "val=query", "", "", "", http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"val","Code":"missing","Message":"is required"}]}`},
+      {"Header", API_PARAM_SOURCE_HEADER, "val=query", "", "header", "", http.StatusOK, `"header"`},
+      {"Header Missing", API_PARAM_SOURCE_HEADER, "val=query", "", "", "", http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"val","Code":"missing","Message":"is required"}]}`},
+      {"Cookie", API_PARAM_SOURCE_COOKIE, "val=query", "", "header", "cookie", http.StatusOK, `"cookie"`},
+      {"Cookie Missing", API_PARAM_SOURCE_COOKIE, "val=query", "", "header", "", http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"val","Code":"missing","Message":"is required"}]}`},
+   };
+
+   for _, test := range(tests) {
+      method := factory.NewApiMethod("/source", handler_echoString, false, []ApiMethodParam{
+         ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_STRING, Required: true, Source: test.source},
+      });
+
+      request := httptest.NewRequest(http.MethodPost, "/source?" + test.query, strings.NewReader(test.body));
+      request.Header.Set("Content-Type", "application/x-www-form-urlencoded");
+      if (test.header != "") {
+         request.Header.Set("val", test.header);
+      }
+      if (test.cookie != "") {
+         request.AddCookie(&http.Cookie{Name: "val", Value: url.QueryEscape(test.cookie)});
+      }
+
+      response := httptest.NewRecorder();
+      method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title, test.status, response.Code);
+         continue;
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
+func TestJSONBody(t *testing.T) {
+   factory := ApiMethodFactory{};
+
+   fields := factory.NewApiMethod("/fields", handler_echoIntString, false, []ApiMethodParam{
+      ApiMethodParam{Name: "count", ParamType: API_PARAM_TYPE_INT, Required: true, Source: API_PARAM_SOURCE_BODY},
+      ApiMethodParam{Name: "name", ParamType: API_PARAM_TYPE_STRING, Required: true},
+   });
+
+   whole := factory.NewApiMethod("/whole", handler_echoJSON, false, []ApiMethodParam{
+      ApiMethodParam{Name: "body", ParamType: API_PARAM_TYPE_JSON, Required: true},
+   });
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+      contentType string
+      body string
+      status int
+      response string
+   } {
+      {"Fields", fields, "application/json", `{"count": 5, "name": "abc"}`, http.StatusOK, `"5 abc"`},
+      {"Fields Charset", fields, "application/json; charset=UTF-8", `{"count": "5", "name": "abc"}`, http.StatusOK, `"5 abc"`},
+      {"Fields Missing", fields, "application/json", `{"count": 5}`, http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"name","Code":"missing","Message":"is required"}]}`},
+      {"Fields Null", fields, "application/json", `{"count": 5, "name": null}`, http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"name","Code":"missing","Message":"is required"}]}`},
+      {"Fields Bad Int", fields, "application/json", `{"count": 5.5, "name": "abc"}`, http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"count","Code":"bad-type","Message":"must be a int"}]}`},
+      {"Fields Bad JSON", fields, "application/json", `{"count": 5`, http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"count","Code":"bad-type","Message":"the request body must be valid JSON of the expected type"}]}`},
+      {"Fields Not JSON", fields, "text/plain", `{"count": 5, "name": "abc"}`, http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"count","Code":"missing","Message":"is required"},{"Name":"name","Code":"missing","Message":"is required"}]}`},
+      {"Whole", whole, "application/json", `{"Count": 5, "Names": ["a", "b"]}`, http.StatusOK, `{"Count":5,"Names":["a","b"]}`},
+      {"Whole Empty", whole, "application/json", ``, http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"body","Code":"missing","Message":"is required"}]}`},
+      {"Whole Bad Type", whole, "application/json", `{"Count": "five"}`, http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"body","Code":"bad-type","Message":"the request body must be valid JSON of the expected type"}]}`},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body));
+      request.Header.Set("Content-Type", test.contentType);
+
+      response := httptest.NewRecorder();
+      test.method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title, test.status, response.Code);
+         continue;
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
+func TestJSONBodyTooLarge(t *testing.T) {
+   factory := ApiMethodFactory{};
+   factory.SetMaxBodySize(32);
+
+   fields := factory.NewApiMethod("/fields", handler_echoIntString, false, []ApiMethodParam{
+      ApiMethodParam{Name: "count", ParamType: API_PARAM_TYPE_INT, Required: true, Source: API_PARAM_SOURCE_BODY},
+      ApiMethodParam{Name: "name", ParamType: API_PARAM_TYPE_STRING, Required: true},
+   });
+
+   whole := factory.NewApiMethod("/whole", handler_echoJSON, false, []ApiMethodParam{
+      ApiMethodParam{Name: "body", ParamType: API_PARAM_TYPE_JSON, Required: true},
+   });
+
+   typed := NewTypedApiMethod(factory, "/typed", false, func(ctx context.Context, request testJSONBody) (interface{}, error) {
+      return request.Count, nil;
+   });
+
+   factory.SetMaxBodySize(-1);
+   unlimited := factory.NewApiMethod("/unlimited", handler_echoIntString, false, []ApiMethodParam{
+      ApiMethodParam{Name: "count", ParamType: API_PARAM_TYPE_INT, Required: true, Source: API_PARAM_SOURCE_BODY},
+      ApiMethodParam{Name: "name", ParamType: API_PARAM_TYPE_STRING, Required: true},
+   });
+
+   var large string = `{"count": 5, "name": "` + strings.Repeat("a", 100) + `"}`;
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+      body string
+      status int
+      response string
+   } {
+      {"Fits", fields, `{"count": 5, "name": "a"}`, http.StatusOK, `"5 a"`},
+      {"Fields", fields, large, http.StatusRequestEntityTooLarge, `{"Success":false,"Code":413}`},
+      {"Whole", whole, large, http.StatusRequestEntityTooLarge, `{"Success":false,"Code":413}`},
+      {"Typed", typed, `{"Count": 5, "Names": ["abcdefghijklmnopqrstuvwxyz"]}`, http.StatusRequestEntityTooLarge, `{"Success":false,"Code":413}`},
+      {"Unlimited", unlimited, large, http.StatusOK, `"5 ` + strings.Repeat("a", 100) + `"`},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body));
+      request.Header.Set("Content-Type", "application/json");
+
+      response := httptest.NewRecorder();
+      test.method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title, test.status, response.Code);
+         continue;
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
+func TestParamsStruct(t *testing.T) {
+   factory := ApiMethodFactory{};
+   method := factory.NewApiMethod("/users/{id}", handler_echoParamsStruct, false, nil);
+
+   tests := []struct{
+      title string
+      path string
+      status int
+      response string
+   } {
+      {"All", "/users/5?page=2&Filter=abc", http.StatusOK, `{"Id":5,"Page":2,"Filter":"abc","Ignored":""}`},
+      {"Default", "/users/5?Filter=abc", http.StatusOK, `{"Id":5,"Page":1,"Filter":"abc","Ignored":""}`},
+      {"Ignored", "/users/5?Filter=abc&Ignored=abc", http.StatusOK, `{"Id":5,"Page":1,"Filter":"abc","Ignored":""}`},
+      {"Missing Required", "/users/5?page=2", http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"Filter","Code":"missing","Message":"is required"}]}`},
+      {"Bad Int", "/users/abc?Filter=abc", http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"id","Code":"bad-type","Message":"must be a int"}]}`},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodGet, test.path, nil);
+      response := httptest.NewRecorder();
+      method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title, test.status, response.Code);
+         continue;
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
+func TestScalarParamTypes(t *testing.T) {
+   factory := ApiMethodFactory{};
+
+   tests := []struct{
+      title string
+      paramType int
+      handler interface{}
+      value string
+      status int
+      response string
+   } {
+      {"Bool", API_PARAM_TYPE_BOOL, func(val bool) interface{} { return val; }, "TRUE", http.StatusOK, `true`},
+      {"Bool Bad", API_PARAM_TYPE_BOOL, func(val bool) interface{} { return val; }, "yes", http.StatusBadRequest, ``},
+      {"Bool Missing", API_PARAM_TYPE_BOOL, func(val bool) interface{} { return val; }, "", http.StatusOK, `false`},
+      {"Float", API_PARAM_TYPE_FLOAT, func(val float64) interface{} { return val; }, "1.5", http.StatusOK, `1.5`},
+      {"Float NaN", API_PARAM_TYPE_FLOAT, func(val float64) interface{} { return val; }, "NaN", http.StatusBadRequest, ``},
+      {"Int64", API_PARAM_TYPE_INT64, func(val int64) interface{} { return val; }, "-9000000000", http.StatusOK, `-9000000000`},
+      {"Int64 Bad", API_PARAM_TYPE_INT64, func(val int64) interface{} { return val; }, "1.0", http.StatusBadRequest, ``},
+      {"Uint", API_PARAM_TYPE_UINT, func(val uint) interface{} { return val; }, "5", http.StatusOK, `5`},
+      {"Uint Negative", API_PARAM_TYPE_UINT, func(val uint) interface{} { return val; }, "-5", http.StatusBadRequest, ``},
+      {"Time", API_PARAM_TYPE_TIME, func(val time.Time) interface{} { return val.Unix(); }, "2020-01-02T03:04:05Z", http.StatusOK, `1577934245`},
+      {"Time Offset", API_PARAM_TYPE_TIME, func(val time.Time) interface{} { return val.Unix(); }, "2020-01-02T04:04:05.5+01:00", http.StatusOK, `1577934245`},
+      {"Time Bad", API_PARAM_TYPE_TIME, func(val time.Time) interface{} { return val.Unix(); }, "2020-01-02", http.StatusBadRequest, ``},
+      {"Duration", API_PARAM_TYPE_DURATION, func(val time.Duration) interface{} { return val.Seconds(); }, "1m30s", http.StatusOK, `90`},
+      {"Duration Bad", API_PARAM_TYPE_DURATION, func(val time.Duration) interface{} { return val.Seconds(); }, "90", http.StatusBadRequest, ``},
+   };
+
+   for _, test := range(tests) {
+      method := factory.NewApiMethod("/scalar", test.handler, false, []ApiMethodParam{
+         ApiMethodParam{Name: "val", ParamType: test.paramType, Required: false},
+      });
+
+      request := httptest.NewRequest(http.MethodGet, "/scalar?val=" + url.QueryEscape(test.value), nil);
+      response := httptest.NewRecorder();
+      method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title, test.status, response.Code);
+         continue;
+      }
+
+      if (test.status == http.StatusOK && strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
+func TestOptionalParams(t *testing.T) {
+   factory := ApiMethodFactory{};
+
+   method := factory.NewApiMethod("/optional", handler_echoOptional, false, []ApiMethodParam{
+      ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT},
+      ApiMethodParam{Name: "someString", ParamType: API_PARAM_TYPE_STRING},
+      ApiMethodParam{Name: "emptyString", ParamType: API_PARAM_TYPE_STRING, AllowEmpty: true},
+   });
+
+   structMethod := factory.NewApiMethod("/optional", func(args struct{
+      SomeInt *int `goapi:"someInt"`
+      SomeString *string `goapi:"someString"`
+      EmptyString *string `goapi:"emptyString,allowempty"`
+   }) interface{} {
+      return handler_echoOptional(args.SomeInt, args.SomeString, args.EmptyString);
+   }, false, nil);
+
+   tests := []struct{
+      title string
+      query string
+      response string
+   } {
+      {"Missing", "", `"nil nil nil"`},
+      {"Zero", "someInt=0&someString=a&emptyString=b", `"0 a b"`},
+      {"Empty", "someInt=&someString=&emptyString=", `"nil nil "`},
+      {"Whitespace", "someInt=%20&someString=%20&emptyString=%20", `"nil nil "`},
+   };
+
+   for _, test := range(tests) {
+      for _, method := range([]*ApiMethod{method, structMethod}) {
+         request := httptest.NewRequest(http.MethodGet, "/optional?" + test.query, nil);
+         response := httptest.NewRecorder();
+         method.Middleware()(response, request);
+
+         if (strings.TrimSpace(response.Body.String()) != test.response) {
+            failTest(t, test.title, test.response, response.Body.String());
+         }
+      }
+   }
+}
+
+func validationTest(t *testing.T, factory ApiMethodFactory, info TestInfo) {
+   defer func() {
+      // Check panic status.
+      r := recover();
+
+      if (r != nil && info.valid) {
+         // Too panicy.
+         t.Errorf("%s: Invalid Panic", info.title)
+      } else if (r == nil && !info.valid) {
+         // Not panicy enough.
+         t.Errorf("%s: Failed to Panic", info.title)
+      }
+   }();
+
+   factory.NewApiMethod(info.path, info.handler, info.auth, info.params);
+}
+
+// Respond with just the violated rule for (the first) ParamError.
+func ruleErrorResponder(err error, httpStatus int) interface{} {
+   var paramErr ParamError;
+   if (errors.As(err, &paramErr) && paramErr.Reason == PARAM_ERROR_CONSTRAINT) {
+      return paramErr.Rule;
+   }
+
+   return GeneralErrorResponder(err, httpStatus);
+}
+
+func fakeValidateToken(token string, log Logger) (userId int, userName string, err error) {
+   return 0, "", nil;
+}
+
+// Test handlers.
+
+func handler_empty() {}
+
+func handler_request(request *http.Request) {}
+
+func handler_response(response http.ResponseWriter) {}
+
+func handler_implicits(id UserId, name UserName, token Token) {}
+
+func handler_userId(id UserId) {}
+
+func handler_userName(name UserName) {}
+
+func handler_token(token Token) {}
+
+func handler_string(someString string) {}
+
+func handler_int(someInt int) {}
+
+func handler_echoOptional(someInt *int, someString *string, emptyString *string) interface{} {
+   var values []string = make([]string, 0);
+
+   if (someInt == nil) {
+      values = append(values, "nil");
+   } else {
+      values = append(values, fmt.Sprintf("%d", *someInt));
+   }
+
+   for _, value := range([]*string{someString, emptyString}) {
+      if (value == nil) {
+         values = append(values, "nil");
+      } else {
+         values = append(values, *value);
+      }
+   }
+
+   return strings.Join(values, " ");
+}
+
+func handler_echoInt(someInt int) interface{} {
+   return someInt;
+}
+
+func handler_echoStrings(someStrings []string) interface{} {
+   return someStrings;
+}
+
+func handler_echoInts(someInts []int) interface{} {
+   return someInts;
+}
+
+func handler_echoString(someString string) interface{} {
+   return someString;
+}
+
+func handler_file(someFile File) {}
+
+type testParamsStruct struct {
+   Id int `goapi:"id,source=path,required"`
+   Page int `goapi:"page,source=query,default=1"`
+   Filter string `goapi:",required"`
+   Ignored string
+}
+
+func handler_paramsStruct(name UserName, args testParamsStruct, id UserId) {}
+
+func handler_echoParamsStruct(args testParamsStruct) interface{} {
+   return args;
+}
+
+type testJSONBody struct {
+   Count int
+   Names []string
+}
+
+func handler_echoJSON(body testJSONBody) interface{} {
+   return body;
+}
+
+func handler_intString(someInt int, someString string) {}
+
+func handler_defaults(pageSize int, since time.Time) {}
+
+func handler_scalars(someBool bool, someFloat float64, someInt64 int64, someUint uint, someTime time.Time, someDuration time.Duration) {}
 
 func handler_intStringFile(someInt int, someString string, file File) {}
 
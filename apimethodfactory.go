@@ -2,6 +2,8 @@ package goapi;
 
 import (
    "fmt"
+   "reflect"
+   "time"
 )
 
 type ApiMethodFactory struct {
@@ -9,7 +11,16 @@ type ApiMethodFactory struct {
    log Logger
    serializer Serializer
    errorResponder ErrorResponder
-   tokenValidator ValidateToken
+   principalValidator ValidatePrincipal
+   basicAuthValidator ValidateBasicAuth
+   basicAuthRealm string
+   tokenExtractors []TokenExtractor
+   panicReporter PanicReporter
+   timeout time.Duration
+   // Zero means the default (DEFAULT_MAX_BODY_SIZE) and negative means no limit.
+   maxBodySize int64
+   // See RegisterInjector().
+   injectors map[reflect.Type]injector
 }
 
 func (factory *ApiMethodFactory) SetLogger(log Logger) {
@@ -28,8 +39,40 @@ func (factory *ApiMethodFactory) SetGeneralErrorResponser(responder ErrorRespond
    factory.errorResponder = responder;
 }
 
+// Set a legacy token validator (see AdaptTokenValidator()).
+// Replaces any principal validator.
 func (factory *ApiMethodFactory) SetTokenValidator(validator ValidateToken) {
-   factory.tokenValidator = validator;
+   factory.principalValidator = AdaptTokenValidator(validator);
+}
+
+// Replaces any token validator.
+func (factory *ApiMethodFactory) SetPrincipalValidator(validator ValidatePrincipal) {
+   factory.principalValidator = validator;
+}
+
+// The extractors that all methods try (in order) to find a request's credential (see TokenExtractor).
+// Calling with no extractors goes back to the defaults.
+func (factory *ApiMethodFactory) SetTokenExtractors(extractors ...TokenExtractor) {
+   factory.setDefaults();
+   factory.tokenExtractors = checkTokenExtractors(factory.log, "ApiMethodFactory", extractors);
+}
+
+// Handler panics are always recovered and logged, use this to also report them somewhere else.
+func (factory *ApiMethodFactory) SetPanicReporter(reporter PanicReporter) {
+   factory.panicReporter = reporter;
+}
+
+// The default timeout for all methods (see ApiMethod.SetTimeout()).
+// Zero (the default) means no timeout.
+func (factory *ApiMethodFactory) SetTimeout(timeout time.Duration) {
+   factory.timeout = timeout;
+}
+
+// The most bytes of a JSON request body that will be read (see DEFAULT_MAX_BODY_SIZE).
+// Requests with larger bodies get a 413 (Request Entity Too Large).
+// A negative size means no limit.
+func (factory *ApiMethodFactory) SetMaxBodySize(size int64) {
+   factory.maxBodySize = size;
 }
 
 // Ensure that defaults are set if there are no user-supplied values.
@@ -52,15 +95,35 @@ func (factory *ApiMethodFactory) setDefaults() {
 }
 
 func (factory ApiMethodFactory) NewApiMethod(path string, handler interface{}, auth bool, params []ApiMethodParam) *ApiMethod {
+   var method ApiMethod = factory.buildApiMethod(path, handler, auth, params);
+
+   method.deriveStructParams();
+   method.validate();
+   method.params = prepareConstraints(method.params);
+   return &method;
+}
+
+// Build a method without doing any validation.
+func (factory ApiMethodFactory) buildApiMethod(path string, handler interface{}, auth bool, params []ApiMethodParam) ApiMethod {
    (&factory).setDefaults();
 
    // Ensure that there is a token validator if authentication is requested.
-   if (auth && factory.tokenValidator == nil) {
-      factory.log.Panic(fmt.Sprintf("API method for [%s] expects authentication, but no token authentication function has been set (see ApiMethodFactory.SetTokenValidator())", path));
+   if (auth && factory.principalValidator == nil && factory.basicAuthValidator == nil) {
+      factory.log.Panic(fmt.Sprintf("API method for [%s] expects authentication, but no token authentication function has been set (see ApiMethodFactory.SetTokenValidator(), ApiMethodFactory.SetPrincipalValidator(), and ApiMethodFactory.SetBasicAuthValidator())", path));
+   }
+
+   if (factory.timeout < 0) {
+      factory.log.Panic(fmt.Sprintf("API method for [%s] has a negative timeout", path));
+   }
+
+   template, err := parsePathTemplate(path);
+   if (err != nil) {
+      factory.log.Panic(fmt.Sprintf("API method for [%s] has a bad path: %v", path, err));
    }
 
    var method ApiMethod = ApiMethod{
       path: path,
+      template: template,
       handler: handler,
       auth: auth,
       params: params,
@@ -69,9 +132,27 @@ func (factory ApiMethodFactory) NewApiMethod(path string, handler interface{}, a
       serializer: factory.serializer,
       contentType: factory.contentType,
       errorResponder: factory.errorResponder,
-      tokenValidator: factory.tokenValidator,
+      principalValidator: factory.principalValidator,
+      basicAuthValidator: factory.basicAuthValidator,
+      basicAuthRealm: factory.basicAuthRealm,
+      tokenExtractors: factory.tokenExtractors,
+      panicReporter: factory.panicReporter,
+      timeout: factory.timeout,
+      maxBodySize: factory.getMaxBodySize(),
+      injectors: factory.injectors,
    };
 
-   method.validate();
-   return &method;
+   return method;
+}
+
+func (factory ApiMethodFactory) getMaxBodySize() int64 {
+   if (factory.maxBodySize == 0) {
+      return DEFAULT_MAX_BODY_SIZE;
+   }
+
+   if (factory.maxBodySize < 0) {
+      return 0;
+   }
+
+   return factory.maxBodySize;
 }
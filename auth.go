@@ -1,3 +1,168 @@
 package goapi;
 
+import (
+   "fmt"
+   "time"
+)
+
+// The legacy validator, only gives a user id and name.
+// See AdaptTokenValidator() to use it as a ValidatePrincipal.
 type ValidateToken func(token string, log Logger) (userId int, userName string, err error)
+
+// Validate a credential and figure out who is making the request.
+// Failures should be TokenValidationErrors (any other error results in a 500).
+type ValidatePrincipal func(credential Credential, log Logger) (*Principal, error)
+
+// The credential that came with a request (see TokenExtractor).
+type Credential struct {
+   // Empty for Basic authentication.
+   Token string
+   // Where the credential came from (see TOKEN_SOURCE_*).
+   Source string
+   // Only set for Basic authentication.
+   UserName string
+   Password string
+}
+
+// Everything we know about who is making an authenticated request.
+// Handlers can take a *Principal as an implicit param (see also ContextPrincipal()).
+type Principal struct {
+   UserId int
+   UserName string
+   // The token the request was authenticated with (filled in automatically if the validator leaves it empty).
+   Token string
+   Roles []string
+   Scopes []string
+   Tenant string
+   // When the credential expires (zero if unknown).
+   ExpiresAt time.Time
+   // Anything else the validator wants to pass along.
+   Claims map[string]interface{}
+}
+
+func (principal Principal) HasRole(role string) bool {
+   return containsString(principal.Roles, role);
+}
+
+func (principal Principal) HasScope(scope string) bool {
+   return containsString(principal.Scopes, scope);
+}
+
+// Let requests without a credential through anonymously.
+// Requests that do have a credential are still validated (and fail if it is bad).
+// Anonymous requests get a user id of -1, an empty user name and token, and a nil *Principal
+// (and ContextPrincipal() and friends will return false).
+// Methods that require roles or scopes do not allow anonymous requests.
+// Will panic if the ApiMethod does not use authentication.
+// Returns this so you can chain.
+func (method *ApiMethod) SetOptionalAuth(optional bool) *ApiMethod {
+   if (!method.auth) {
+      method.log.Panic(fmt.Sprintf("API handler (%s) has optional authentication without authentication", method.path));
+   }
+
+   method.optionalAuth = optional;
+   return method;
+}
+
+// Require the principal to have ALL of |roles| (in addition to any scopes required).
+// Principals that are missing any will get a 403 (Forbidden) with an AuthorizationError.
+// Calling with no roles removes the requirement.
+// Will panic if the ApiMethod does not use authentication.
+// Returns this so you can chain.
+func (method *ApiMethod) SetRequiredRoles(roles ...string) *ApiMethod {
+   method.requiredRoles = method.checkRequirements("role", roles);
+   return method;
+}
+
+// Require the principal to have ALL of |scopes| (in addition to any roles required).
+// See SetRequiredRoles().
+// Returns this so you can chain.
+func (method *ApiMethod) SetRequiredScopes(scopes ...string) *ApiMethod {
+   method.requiredScopes = method.checkRequirements("scope", scopes);
+   return method;
+}
+
+func (method ApiMethod) checkRequirements(kind string, values []string) []string {
+   if (!method.auth) {
+      method.log.Panic(fmt.Sprintf("API handler (%s) requires a %s without authentication", method.path, kind));
+   }
+
+   if (len(values) == 0) {
+      return nil;
+   }
+
+   var rtn []string = make([]string, 0, len(values));
+   for _, value := range(values) {
+      if (value == "") {
+         method.log.Panic(fmt.Sprintf("API handler (%s) requires an empty %s", method.path, kind));
+      }
+
+      rtn = append(rtn, value);
+   }
+
+   return rtn;
+}
+
+// Check that |principal| has all the method's required roles and scopes.
+func (method ApiMethod) authorize(principal *Principal) (AuthorizationError, bool) {
+   var err AuthorizationError = AuthorizationError{};
+
+   for _, role := range(method.requiredRoles) {
+      if (!principal.HasRole(role)) {
+         err.MissingRoles = append(err.MissingRoles, role);
+      }
+   }
+
+   for _, scope := range(method.requiredScopes) {
+      if (!principal.HasScope(scope)) {
+         err.MissingScopes = append(err.MissingScopes, scope);
+      }
+   }
+
+   return err, (len(err.MissingRoles) == 0 && len(err.MissingScopes) == 0);
+}
+
+// Use a legacy ValidateToken as a ValidatePrincipal.
+func AdaptTokenValidator(validator ValidateToken) ValidatePrincipal {
+   if (validator == nil) {
+      return nil;
+   }
+
+   return func(credential Credential, log Logger) (*Principal, error) {
+      userId, userName, err := validator(credential.Token, log);
+      if (err != nil) {
+         return nil, err;
+      }
+
+      return &Principal{UserId: userId, UserName: userName, Token: credential.Token}, nil;
+   };
+}
+
+// Run the validator and make a copy of the resulting principal (so validators can share principals).
+func validatePrincipal(validator ValidatePrincipal, credential Credential, log Logger) (*Principal, error) {
+   result, err := validator(credential, log);
+   if (err != nil) {
+      return nil, err;
+   }
+
+   if (result == nil) {
+      return nil, fmt.Errorf("Principal validator returned neither a principal nor an error");
+   }
+
+   var principal Principal = *result;
+   if (principal.Token == "") {
+      principal.Token = credential.Token;
+   }
+
+   return &principal, nil;
+}
+
+func containsString(values []string, target string) bool {
+   for _, value := range(values) {
+      if (value == target) {
+         return true;
+      }
+   }
+
+   return false;
+}
@@ -0,0 +1,259 @@
+package goapi;
+
+import (
+   "context"
+   "fmt"
+   "net/http"
+   "net/http/httptest"
+   "strings"
+   "testing"
+);
+
+func fakeValidatePrincipal(credential Credential, log Logger) (*Principal, error) {
+   switch (credential.Token) {
+   case "admin":
+      return &Principal{UserId: 1, UserName: "alice", Roles: []string{"admin"}, Scopes: []string{"read", "write"}, Tenant: "acme"}, nil;
+   case "nil":
+      return nil, nil;
+   case "broken":
+      return nil, fmt.Errorf("Database is down");
+   case "expired":
+      return nil, TokenValidationError{TOKEN_VALIDATION_EXPIRED};
+   default:
+      return &Principal{UserId: 2, UserName: "bob", Scopes: []string{"read"}}, nil;
+   }
+}
+
+func TestPrincipal(t *testing.T) {
+   factory := ApiMethodFactory{};
+   factory.SetPrincipalValidator(fakeValidatePrincipal);
+
+   basic := factory.NewApiMethod("/principal", func(principal *Principal, userId UserId, token Token) interface{} {
+      return fmt.Sprintf("%d %d %s %s %s %v %v", userId, principal.UserId, principal.UserName, principal.Tenant, token, principal.HasRole("admin"), principal.HasScope("write"));
+   }, true, []ApiMethodParam{});
+
+   typed := NewTypedApiMethod(factory, "/typed", true, func(ctx context.Context, request struct{}) (interface{}, error) {
+      principal, ok := ContextPrincipal(ctx);
+      return fmt.Sprintf("%v %s %s", ok, principal.UserName, principal.Token), nil;
+   });
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+      token string
+      status int
+      response string
+   } {
+      {"Admin", basic, "admin", http.StatusOK, `"1 1 alice acme admin true true"`},
+      {"User", basic, "user", http.StatusOK, `"2 2 bob  user false false"`},
+      {"Typed", typed, "admin", http.StatusOK, `"true alice admin"`},
+      {"Nil Principal", basic, "nil", http.StatusInternalServerError, `{"Success":false,"Code":500}`},
+      {"Other Error", basic, "broken", http.StatusInternalServerError, `{"Success":false,"Code":500}`},
+      {"Expired", basic, "expired", http.StatusUnauthorized, `{"Success":false,"Code":401}`},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodGet, "/", nil);
+      request.Header.Set("Authorization", "Bearer " + test.token);
+
+      response := httptest.NewRecorder();
+      test.method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title + " (status)", test.status, response.Code);
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
+func TestAdaptTokenValidator(t *testing.T) {
+   validator := AdaptTokenValidator(fakeValidateToken);
+
+   principal, err := validator(Credential{Token: "TOKEN"}, ConsoleLogger{});
+   if (err != nil) {
+      t.Fatalf("Unexpected error: %v", err);
+   }
+
+   if (principal.UserId != 0 || principal.UserName != "" || principal.Token != "TOKEN") {
+      failTest(t, "Adapted", "user 0 with the token", *principal);
+   }
+
+   if (AdaptTokenValidator(nil) != nil) {
+      failTest(t, "Nil", "nil", "a validator");
+   }
+}
+
+func TestPrincipalValidation(t *testing.T) {
+   defer func() {
+      if (recover() == nil) {
+         t.Errorf("Principal Without Auth: Failed to Panic");
+      }
+   }();
+
+   factory := ApiMethodFactory{};
+   factory.SetPrincipalValidator(fakeValidatePrincipal);
+   factory.NewApiMethod("/principal", func(principal *Principal) {}, false, []ApiMethodParam{});
+}
+
+func TestAuthorization(t *testing.T) {
+   var authErr error = nil;
+
+   factory := ApiMethodFactory{};
+   factory.SetPrincipalValidator(fakeValidatePrincipal);
+   factory.SetGeneralErrorResponser(func(err error, httpStatus int) interface{} {
+      authErr = err;
+      return GeneralErrorResponder(err, httpStatus);
+   });
+
+   handler := func(userId UserId) interface{} {
+      return userId;
+   };
+
+   admin := factory.NewApiMethod("/admin", handler, true, []ApiMethodParam{}).SetRequiredRoles("admin");
+   writer := factory.NewApiMethod("/write", handler, true, []ApiMethodParam{}).SetRequiredScopes("read", "write");
+   both := factory.NewApiMethod("/both", handler, true, []ApiMethodParam{}).SetRequiredRoles("admin", "owner").SetRequiredScopes("read");
+   cleared := factory.NewApiMethod("/cleared", handler, true, []ApiMethodParam{}).SetRequiredRoles("admin").SetRequiredRoles();
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+      token string
+      status int
+      response string
+      err string
+   } {
+      {"Role", admin, "admin", http.StatusOK, `1`, ""},
+      {"Missing Role", admin, "user", http.StatusForbidden, `{"Success":false,"Code":403}`, "Insufficient permissions (missing roles: [admin], missing scopes: [])"},
+      {"Scopes", writer, "admin", http.StatusOK, `1`, ""},
+      {"Missing Scope", writer, "user", http.StatusForbidden, `{"Success":false,"Code":403}`, "Insufficient permissions (missing roles: [], missing scopes: [write])"},
+      {"Missing Some", both, "admin", http.StatusForbidden, `{"Success":false,"Code":403}`, "Insufficient permissions (missing roles: [owner], missing scopes: [])"},
+      {"Bad Token", admin, "expired", http.StatusUnauthorized, `{"Success":false,"Code":401}`, "Token is expired"},
+      {"Cleared", cleared, "user", http.StatusOK, `2`, ""},
+   };
+
+   for _, test := range(tests) {
+      authErr = nil;
+
+      request := httptest.NewRequest(http.MethodGet, "/", nil);
+      request.Header.Set("Authorization", "Bearer " + test.token);
+
+      response := httptest.NewRecorder();
+      test.method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title + " (status)", test.status, response.Code);
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+
+      var errString string = "";
+      if (authErr != nil) {
+         errString = authErr.Error();
+      }
+
+      if (errString != test.err) {
+         failTest(t, test.title + " (error)", test.err, errString);
+      }
+   }
+}
+
+func TestAuthorizationValidation(t *testing.T) {
+   tests := []struct{
+      title string
+      build func(factory ApiMethodFactory)
+   } {
+      {"Roles Without Auth", func(factory ApiMethodFactory) {
+         factory.NewApiMethod("/roles", func() {}, false, []ApiMethodParam{}).SetRequiredRoles("admin");
+      }},
+      {"Scopes Without Auth", func(factory ApiMethodFactory) {
+         factory.NewApiMethod("/scopes", func() {}, false, []ApiMethodParam{}).SetRequiredScopes("read");
+      }},
+      {"Optional Without Auth", func(factory ApiMethodFactory) {
+         factory.NewApiMethod("/optional", func() {}, false, []ApiMethodParam{}).SetOptionalAuth(true);
+      }},
+      {"Empty Role", func(factory ApiMethodFactory) {
+         factory.NewApiMethod("/roles", func() {}, true, []ApiMethodParam{}).SetRequiredRoles("admin", "");
+      }},
+   };
+
+   for _, test := range(tests) {
+      func() {
+         defer func() {
+            if (recover() == nil) {
+               t.Errorf("%s: Failed to Panic", test.title);
+            }
+         }();
+
+         factory := ApiMethodFactory{};
+         factory.SetPrincipalValidator(fakeValidatePrincipal);
+         test.build(factory);
+      }();
+   }
+}
+
+func TestOptionalAuth(t *testing.T) {
+   factory := ApiMethodFactory{};
+   factory.SetPrincipalValidator(fakeValidatePrincipal);
+
+   basic := factory.NewApiMethod("/optional", func(userId UserId, principal *Principal) interface{} {
+      if (principal == nil) {
+         return fmt.Sprintf("anonymous %d", userId);
+      }
+
+      return fmt.Sprintf("%s %d", principal.UserName, userId);
+   }, true, []ApiMethodParam{}).SetOptionalAuth(true);
+
+   typed := NewTypedApiMethod(factory, "/typed", true, func(ctx context.Context, request struct{}) (interface{}, error) {
+      _, ok := ContextPrincipal(ctx);
+      userId, _ := ContextUserId(ctx);
+      return fmt.Sprintf("%v %d", ok, userId), nil;
+   }).SetOptionalAuth(true);
+
+   admin := factory.NewApiMethod("/admin", func(userId UserId) interface{} {
+      return userId;
+   }, true, []ApiMethodParam{}).SetOptionalAuth(true).SetRequiredRoles("admin");
+
+   required := factory.NewApiMethod("/required", func(userId UserId) interface{} {
+      return userId;
+   }, true, []ApiMethodParam{}).SetOptionalAuth(true).SetOptionalAuth(false);
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+      token string
+      status int
+      response string
+   } {
+      {"Anonymous", basic, "", http.StatusOK, `"anonymous -1"`},
+      {"User", basic, "user", http.StatusOK, `"bob 2"`},
+      {"Bad Token", basic, "expired", http.StatusUnauthorized, `{"Success":false,"Code":401}`},
+      {"Typed Anonymous", typed, "", http.StatusOK, `"false 0"`},
+      {"Typed User", typed, "admin", http.StatusOK, `"true 1"`},
+      {"Roles Anonymous", admin, "", http.StatusUnauthorized, `{"Success":false,"Code":401}`},
+      {"Roles Admin", admin, "admin", http.StatusOK, `1`},
+      {"Turned Off", required, "", http.StatusUnauthorized, `{"Success":false,"Code":401}`},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodGet, "/", nil);
+      if (test.token != "") {
+         request.Header.Set("Authorization", "Bearer " + test.token);
+      }
+
+      response := httptest.NewRecorder();
+      test.method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title + " (status)", test.status, response.Code);
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
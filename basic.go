@@ -0,0 +1,70 @@
+package goapi;
+
+import (
+   "fmt"
+   "strings"
+)
+
+// Check the user name and password from HTTP Basic authentication.
+// Return false (with no error) if the credentials are wrong, the request will fail with TOKEN_AUTH_BAD_CREDENTIALS.
+// A nil principal is allowed on success, then a principal with just the user name is used.
+// Any error that is not a TokenValidationError results in a 500.
+type ValidateBasicAuth func(userName string, password string, log Logger) (principal *Principal, ok bool, err error)
+
+const DEFAULT_BASIC_AUTH_REALM = "API"
+
+// Accept HTTP Basic authentication ("Authorization: Basic ...") using |validator|.
+// Basic auth is checked before the default token lookup (see TokenExtractor)
+// and any 401 (Unauthorized) will include a "WWW-Authenticate" challenge for |realm|
+// (DEFAULT_BASIC_AUTH_REALM if empty).
+// This works alongside a token validator: Basic credentials go to |validator| and tokens go to the token validator.
+func (factory *ApiMethodFactory) SetBasicAuthValidator(validator ValidateBasicAuth, realm string) {
+   if (realm == "") {
+      realm = DEFAULT_BASIC_AUTH_REALM;
+   }
+
+   factory.basicAuthValidator = validator;
+   factory.basicAuthRealm = realm;
+}
+
+func basicAuthChallenge(realm string) string {
+   var escaped string = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(realm);
+   return fmt.Sprintf(`Basic realm="%s", charset="UTF-8"`, escaped);
+}
+
+// Send a credential to the validator for its kind.
+func (method ApiMethod) validateCredential(credential Credential) (*Principal, error) {
+   if (credential.Source == TOKEN_SOURCE_BASIC) {
+      // Basic credentials have no token, so they cannot go to the token validator.
+      if (method.basicAuthValidator == nil) {
+         return nil, TokenValidationError{TOKEN_AUTH_BAD_CREDENTIALS};
+      }
+
+      return validateBasicAuth(method.basicAuthValidator, credential, method.log);
+   }
+
+   // A token, but we only know how to check Basic credentials.
+   if (method.principalValidator == nil) {
+      return nil, TokenValidationError{TOKEN_AUTH_BAD_CREDENTIALS};
+   }
+
+   return validatePrincipal(method.principalValidator, credential, method.log);
+}
+
+func validateBasicAuth(validator ValidateBasicAuth, credential Credential, log Logger) (*Principal, error) {
+   result, ok, err := validator(credential.UserName, credential.Password, log);
+   if (err != nil) {
+      return nil, err;
+   }
+
+   if (!ok) {
+      return nil, TokenValidationError{TOKEN_AUTH_BAD_CREDENTIALS};
+   }
+
+   var principal Principal = Principal{UserName: credential.UserName};
+   if (result != nil) {
+      principal = *result;
+   }
+
+   return &principal, nil;
+}
@@ -0,0 +1,118 @@
+package goapi;
+
+import (
+   "fmt"
+   "net/http"
+   "net/http/httptest"
+   "strings"
+   "testing"
+);
+
+func fakeValidateBasicAuth(userName string, password string, log Logger) (*Principal, bool, error) {
+   if (userName == "broken") {
+      return nil, false, fmt.Errorf("Database is down");
+   }
+
+   if (password != "secret") {
+      return nil, false, nil;
+   }
+
+   if (userName == "alice") {
+      return &Principal{UserId: 1, UserName: "alice", Roles: []string{"admin"}}, true, nil;
+   }
+
+   return nil, true, nil;
+}
+
+func TestBasicAuth(t *testing.T) {
+   handler := func(userId UserId, userName UserName, token Token) interface{} {
+      return fmt.Sprintf("%d %s %s", userId, userName, token);
+   };
+
+   basicFactory := ApiMethodFactory{};
+   basicFactory.SetBasicAuthValidator(fakeValidateBasicAuth, "Internal \"Tools\"");
+   basicOnly := basicFactory.NewApiMethod("/basic", handler, true, []ApiMethodParam{});
+   admin := basicFactory.NewApiMethod("/admin", handler, true, []ApiMethodParam{}).SetRequiredRoles("admin");
+
+   mixedFactory := ApiMethodFactory{};
+   mixedFactory.SetTokenValidator(func(token string, log Logger) (int, string, error) {
+      return 2, "bob", nil;
+   });
+   mixedFactory.SetBasicAuthValidator(fakeValidateBasicAuth, "");
+   mixed := mixedFactory.NewApiMethod("/mixed", handler, true, []ApiMethodParam{});
+
+   // Basic credentials without a Basic auth validator.
+   tokenFactory := ApiMethodFactory{};
+   tokenFactory.SetTokenValidator(func(token string, log Logger) (int, string, error) {
+      return 2, "bob", nil;
+   });
+   tokenOnly := tokenFactory.NewApiMethod("/token", handler, true, []ApiMethodParam{}).SetTokenExtractors(BasicAuthExtractor(), BearerTokenExtractor());
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+      header string
+      status int
+      response string
+      challenge string
+   } {
+      {"Principal", basicOnly, "Basic " + basicAuth("alice", "secret"), http.StatusOK, `"1 alice "`, ""},
+      {"No Principal", basicOnly, "Basic " + basicAuth("carol", "secret"), http.StatusOK, `"0 carol "`, ""},
+      {"Bad Password", basicOnly, "Basic " + basicAuth("alice", "wrong"), http.StatusUnauthorized, `{"Success":false,"Code":401}`, `Basic realm="Internal \"Tools\"", charset="UTF-8"`},
+      {"Missing", basicOnly, "", http.StatusUnauthorized, `{"Success":false,"Code":401}`, `Basic realm="Internal \"Tools\"", charset="UTF-8"`},
+      {"Bearer Ignored", basicOnly, "Bearer abc", http.StatusUnauthorized, `{"Success":false,"Code":401}`, `Basic realm="Internal \"Tools\"", charset="UTF-8"`},
+      {"Error", basicOnly, "Basic " + basicAuth("broken", "secret"), http.StatusInternalServerError, `{"Success":false,"Code":500}`, ""},
+      {"Forbidden", admin, "Basic " + basicAuth("carol", "secret"), http.StatusForbidden, `{"Success":false,"Code":403}`, ""},
+      {"Mixed Bearer", mixed, "Bearer abc", http.StatusOK, `"2 bob abc"`, ""},
+      {"Mixed Basic", mixed, "Basic " + basicAuth("alice", "secret"), http.StatusOK, `"1 alice "`, ""},
+      {"No Basic Validator", tokenOnly, "Basic " + basicAuth("alice", "secret"), http.StatusUnauthorized, `{"Success":false,"Code":401}`, `Basic realm="API", charset="UTF-8"`},
+      {"No Basic Validator Bearer", tokenOnly, "Bearer abc", http.StatusOK, `"2 bob abc"`, ""},
+      {"Mixed Bad Password", mixed, "Basic " + basicAuth("alice", "wrong"), http.StatusUnauthorized, `{"Success":false,"Code":401}`, `Basic realm="API", charset="UTF-8"`},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodGet, "/", nil);
+      if (test.header != "") {
+         request.Header.Set("Authorization", test.header);
+      }
+
+      response := httptest.NewRecorder();
+      test.method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title + " (status)", test.status, response.Code);
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+
+      if (response.Header().Get("WWW-Authenticate") != test.challenge) {
+         failTest(t, test.title + " (challenge)", test.challenge, response.Header().Get("WWW-Authenticate"));
+      }
+   }
+}
+
+func TestBasicAuthBadCredentials(t *testing.T) {
+   factory := ApiMethodFactory{};
+   factory.SetGeneralErrorResponser(ProblemErrorResponder);
+   factory.SetBasicAuthValidator(fakeValidateBasicAuth, "");
+   method := factory.NewApiMethod("/basic", handler_empty, true, []ApiMethodParam{});
+
+   request := httptest.NewRequest(http.MethodGet, "/basic", nil);
+   request.SetBasicAuth("alice", "wrong");
+
+   response := httptest.NewRecorder();
+   method.Middleware()(response, request);
+
+   var expected string = `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"Bad credentials","instance":"/basic","token-reason":"bad-credentials"}`;
+   if (strings.TrimSpace(response.Body.String()) != expected) {
+      failTest(t, "Bad Credentials", expected, response.Body.String());
+   }
+}
+
+func basicAuth(userName string, password string) string {
+   request := httptest.NewRequest(http.MethodGet, "/", nil);
+   request.SetBasicAuth(userName, password);
+   return strings.TrimPrefix(request.Header.Get("Authorization"), "Basic ");
+}
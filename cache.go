@@ -0,0 +1,238 @@
+package goapi;
+
+import (
+   "container/list"
+   "fmt"
+   "sync"
+   "time"
+)
+
+// The configuration for a TokenCache (see NewTokenCache()).
+type TokenCacheConfig struct {
+   // How long a valid token is remembered (required).
+   // Tokens are never remembered past their principal's ExpiresAt.
+   TTL time.Duration
+   // How long a revoked token (TOKEN_VALIDATION_REVOKED) is remembered.
+   // Zero means revoked tokens are not remembered.
+   RevokedTTL time.Duration
+   // The most tokens to remember, the least recently used are dropped first.
+   // Zero means no limit.
+   MaxEntries int
+   // The current time, defaults to time.Now (useful for testing).
+   Now func() time.Time
+}
+
+// Remembers the results of a (slow) principal validator so that every request does not need to call it.
+// Use TokenCache.Validate as the factory's principal validator:
+//    cache, err := goapi.NewTokenCache(goapi.AdaptTokenValidator(validateToken), goapi.TokenCacheConfig{TTL: time.Minute});
+//    factory.SetPrincipalValidator(cache.Validate);
+// Only tokens are cached, Basic auth credentials always go to the validator.
+// Errors other than revoked tokens are never cached.
+// Principals are (deep) copied going into and out of the cache, so changes to them never reach other requests.
+// Safe for concurrent use.
+// The validator is called without holding any locks, so concurrent requests for the same (uncached) token
+// may all call the validator.
+type TokenCache struct {
+   validator ValidatePrincipal
+   config TokenCacheConfig
+
+   mutex sync.Mutex
+   // Values are *tokenCacheEntry, the front is the most recently used.
+   order *list.List
+   entries map[string]*list.Element
+   // Bumped on every invalidation so that validations that were running at the time are not cached.
+   generation uint64
+}
+
+type tokenCacheEntry struct {
+   token string
+   principal *Principal
+   err error
+   expires time.Time
+}
+
+func NewTokenCache(validator ValidatePrincipal, config TokenCacheConfig) (*TokenCache, error) {
+   if (validator == nil) {
+      return nil, fmt.Errorf("Token cache requires a validator");
+   }
+
+   if (config.TTL <= 0) {
+      return nil, fmt.Errorf("Token cache requires a positive TTL, got %v", config.TTL);
+   }
+
+   if (config.RevokedTTL < 0) {
+      return nil, fmt.Errorf("Token cache has a negative revoked TTL (%v)", config.RevokedTTL);
+   }
+
+   if (config.MaxEntries < 0) {
+      return nil, fmt.Errorf("Token cache has a negative max entries (%d)", config.MaxEntries);
+   }
+
+   if (config.Now == nil) {
+      config.Now = time.Now;
+   }
+
+   return &TokenCache{
+      validator: validator,
+      config: config,
+      order: list.New(),
+      entries: make(map[string]*list.Element),
+   }, nil;
+}
+
+// A ValidatePrincipal that checks the cache before calling the real validator.
+func (cache *TokenCache) Validate(credential Credential, log Logger) (*Principal, error) {
+   if (credential.Source == TOKEN_SOURCE_BASIC || credential.Token == "") {
+      return cache.validator(credential, log);
+   }
+
+   entry, generation, ok := cache.get(credential.Token);
+   if (ok) {
+      if (entry.err != nil) {
+         return nil, entry.err;
+      }
+
+      return copyPrincipal(entry.principal), nil;
+   }
+
+   principal, err := cache.validator(credential, log);
+   if (err != nil) {
+      validationErr, ok := err.(TokenValidationError);
+      if (ok && validationErr.Reason == TOKEN_VALIDATION_REVOKED && cache.config.RevokedTTL > 0) {
+         cache.put(generation, &tokenCacheEntry{token: credential.Token, err: err, expires: cache.config.Now().Add(cache.config.RevokedTTL)});
+      }
+
+      return nil, err;
+   }
+
+   if (principal != nil) {
+      var expires time.Time = cache.config.Now().Add(cache.config.TTL);
+      if (!principal.ExpiresAt.IsZero() && principal.ExpiresAt.Before(expires)) {
+         expires = principal.ExpiresAt;
+      }
+
+      cache.put(generation, &tokenCacheEntry{token: credential.Token, principal: copyPrincipal(principal), expires: expires});
+   }
+
+   return principal, nil;
+}
+
+// Forget a token (eg when a user logs out).
+func (cache *TokenCache) Invalidate(token string) {
+   cache.mutex.Lock();
+   defer cache.mutex.Unlock();
+
+   cache.generation++;
+
+   element, ok := cache.entries[token];
+   if (ok) {
+      cache.remove(element);
+   }
+}
+
+// Forget all tokens.
+func (cache *TokenCache) InvalidateAll() {
+   cache.mutex.Lock();
+   defer cache.mutex.Unlock();
+
+   cache.generation++;
+   cache.order.Init();
+   cache.entries = make(map[string]*list.Element);
+}
+
+// The number of tokens currently remembered (including any that have expired but not been dropped yet).
+func (cache *TokenCache) Len() int {
+   cache.mutex.Lock();
+   defer cache.mutex.Unlock();
+
+   return cache.order.Len();
+}
+
+// Get a live entry for |token|.
+// Also returns the current generation (to pass to put()).
+func (cache *TokenCache) get(token string) (*tokenCacheEntry, uint64, bool) {
+   cache.mutex.Lock();
+   defer cache.mutex.Unlock();
+
+   element, ok := cache.entries[token];
+   if (!ok) {
+      return nil, cache.generation, false;
+   }
+
+   var entry *tokenCacheEntry = element.Value.(*tokenCacheEntry);
+   if (!cache.config.Now().Before(entry.expires)) {
+      cache.remove(element);
+      return nil, cache.generation, false;
+   }
+
+   cache.order.MoveToFront(element);
+   return entry, cache.generation, true;
+}
+
+// Store an entry, unless there has been an invalidation since |generation|.
+func (cache *TokenCache) put(generation uint64, entry *tokenCacheEntry) {
+   cache.mutex.Lock();
+   defer cache.mutex.Unlock();
+
+   if (generation != cache.generation) {
+      return;
+   }
+
+   element, ok := cache.entries[entry.token];
+   if (ok) {
+      cache.remove(element);
+   }
+
+   cache.entries[entry.token] = cache.order.PushFront(entry);
+
+   for (cache.config.MaxEntries > 0 && cache.order.Len() > cache.config.MaxEntries) {
+      cache.remove(cache.order.Back());
+   }
+}
+
+// The lock must be held.
+func (cache *TokenCache) remove(element *list.Element) {
+   cache.order.Remove(element);
+   delete(cache.entries, element.Value.(*tokenCacheEntry).token);
+}
+
+// Copy a principal so that neither the validator nor handlers can change what is cached.
+// Claims are copied as deep as JSON-like values (maps and slices) go, anything else is shared.
+func copyPrincipal(principal *Principal) *Principal {
+   var rtn Principal = *principal;
+
+   if (principal.Roles != nil) {
+      rtn.Roles = append([]string{}, principal.Roles...);
+   }
+
+   if (principal.Scopes != nil) {
+      rtn.Scopes = append([]string{}, principal.Scopes...);
+   }
+
+   if (principal.Claims != nil) {
+      rtn.Claims = copyClaim(principal.Claims).(map[string]interface{});
+   }
+
+   return &rtn;
+}
+
+func copyClaim(value interface{}) interface{} {
+   switch typedValue := value.(type) {
+   case map[string]interface{}:
+      var rtn map[string]interface{} = make(map[string]interface{}, len(typedValue));
+      for key, child := range(typedValue) {
+         rtn[key] = copyClaim(child);
+      }
+      return rtn;
+   case []interface{}:
+      var rtn []interface{} = make([]interface{}, len(typedValue));
+      for i, child := range(typedValue) {
+         rtn[i] = copyClaim(child);
+      }
+      return rtn;
+   case []string:
+      return append([]string{}, typedValue...);
+   default:
+      return value;
+   }
+}
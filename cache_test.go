@@ -0,0 +1,217 @@
+package goapi;
+
+import (
+   "fmt"
+   "sync"
+   "testing"
+   "time"
+);
+
+type countingValidator struct {
+   mutex sync.Mutex
+   calls map[string]int
+}
+
+func (validator *countingValidator) validate(credential Credential, log Logger) (*Principal, error) {
+   validator.mutex.Lock();
+   validator.calls[credential.Token]++;
+   validator.mutex.Unlock();
+
+   switch (credential.Token) {
+   case "revoked":
+      return nil, TokenValidationError{TOKEN_VALIDATION_REVOKED};
+   case "expired":
+      return nil, TokenValidationError{TOKEN_VALIDATION_EXPIRED};
+   case "broken":
+      return nil, fmt.Errorf("Database is down");
+   case "short":
+      return &Principal{UserName: "short", ExpiresAt: testJWTNow.Add(time.Second)}, nil;
+   case "claims":
+      return &Principal{UserName: "claims", Roles: []string{"user"}, Scopes: []string{"read"}, Claims: map[string]interface{}{"groups": []interface{}{"a"}}}, nil;
+   default:
+      return &Principal{UserName: credential.Token}, nil;
+   }
+}
+
+func (validator *countingValidator) count(token string) int {
+   validator.mutex.Lock();
+   defer validator.mutex.Unlock();
+
+   return validator.calls[token];
+}
+
+func TestTokenCache(t *testing.T) {
+   var now time.Time = testJWTNow;
+   validator := &countingValidator{calls: make(map[string]int)};
+
+   cache, err := NewTokenCache(validator.validate, TokenCacheConfig{
+      TTL: time.Minute,
+      RevokedTTL: time.Hour,
+      MaxEntries: 3,
+      Now: func() time.Time { return now; },
+   });
+   if (err != nil) {
+      t.Fatalf("Unexpected error: %v", err);
+   }
+
+   validate := func(token string) string {
+      principal, err := cache.Validate(Credential{Token: token, Source: TOKEN_SOURCE_BEARER}, ConsoleLogger{});
+      if (err != nil) {
+         return err.Error();
+      }
+
+      return principal.UserName;
+   };
+
+   steps := []struct{
+      title string
+      action func()
+      token string
+      response string
+      calls int
+   } {
+      {"First", nil, "a", "a", 1},
+      {"Cached", nil, "a", "a", 1},
+      {"TTL", func() { now = now.Add(time.Minute); }, "a", "a", 2},
+      {"Revoked", nil, "revoked", "Token has been revoked", 1},
+      {"Revoked Cached", func() { now = now.Add(30 * time.Minute); }, "revoked", "Token has been revoked", 1},
+      {"Expired Not Cached", nil, "expired", "Token is expired", 1},
+      {"Expired Not Cached Again", nil, "expired", "Token is expired", 2},
+      {"Error Not Cached", nil, "broken", "Database is down", 1},
+      {"Error Not Cached Again", nil, "broken", "Database is down", 2},
+      {"Principal Expiry", func() { now = testJWTNow; }, "short", "short", 1},
+      {"Principal Expiry Cached", nil, "short", "short", 1},
+      {"Principal Expired", func() { now = testJWTNow.Add(2 * time.Second); }, "short", "short", 2},
+      {"Invalidate", func() { cache.Invalidate("a"); }, "a", "a", 3},
+      {"After Invalidate", nil, "a", "a", 3},
+      {"Invalidate All", func() { cache.InvalidateAll(); }, "a", "a", 4},
+      {"Revoked Invalidated", nil, "revoked", "Token has been revoked", 2},
+      // "a", "revoked", then "b" and "c" push out the least recently used ("a").
+      {"Fill", func() { validate("b"); validate("c"); }, "a", "a", 5},
+      {"LRU Kept", nil, "c", "c", 1},
+      {"LRU Evicted", nil, "revoked", "Token has been revoked", 3},
+   };
+
+   for _, step := range(steps) {
+      if (step.action != nil) {
+         step.action();
+      }
+
+      var response string = validate(step.token);
+      if (response != step.response) {
+         failTest(t, step.title, step.response, response);
+      }
+
+      if (validator.count(step.token) != step.calls) {
+         failTest(t, step.title + " (calls)", step.calls, validator.count(step.token));
+      }
+   }
+
+   if (cache.Len() != 3) {
+      failTest(t, "Len", 3, cache.Len());
+   }
+}
+
+func TestTokenCacheCopies(t *testing.T) {
+   validator := &countingValidator{calls: make(map[string]int)};
+   cache, _ := NewTokenCache(validator.validate, TokenCacheConfig{TTL: time.Minute});
+
+   principal, _ := cache.Validate(Credential{Token: "a"}, ConsoleLogger{});
+   principal.UserName = "changed";
+
+   principal, _ = cache.Validate(Credential{Token: "a"}, ConsoleLogger{});
+   if (principal.UserName != "a") {
+      failTest(t, "Copies", "a", principal.UserName);
+   }
+
+   // Changes to the validator's principal (when it is stored) and to a returned principal (when it is read)
+   // should not reach the cache.
+   principal, _ = cache.Validate(Credential{Token: "claims"}, ConsoleLogger{});
+   principal.Roles[0] = "admin";
+   principal.Scopes[0] = "write";
+   principal.Claims["groups"].([]interface{})[0] = "b";
+   principal.Claims["extra"] = true;
+
+   principal, _ = cache.Validate(Credential{Token: "claims"}, ConsoleLogger{});
+   var deep string = fmt.Sprintf("%v %v %v", principal.Roles, principal.Scopes, principal.Claims);
+   if (deep != "[user] [read] map[groups:[a]]") {
+      failTest(t, "Deep Copies", "[user] [read] map[groups:[a]]", deep);
+   }
+
+   principal.Roles = append(principal.Roles[:0], "admin");
+   principal.Claims["groups"] = nil;
+
+   principal, _ = cache.Validate(Credential{Token: "claims"}, ConsoleLogger{});
+   deep = fmt.Sprintf("%v %v %v", principal.Roles, principal.Scopes, principal.Claims);
+   if (deep != "[user] [read] map[groups:[a]]") {
+      failTest(t, "Deep Copies (read)", "[user] [read] map[groups:[a]]", deep);
+   }
+
+   if (validator.count("claims") != 1) {
+      failTest(t, "Deep Copies (calls)", 1, validator.count("claims"));
+   }
+
+   // Basic credentials are never cached.
+   cache.Validate(Credential{Source: TOKEN_SOURCE_BASIC, UserName: "alice", Password: "secret"}, ConsoleLogger{});
+   cache.Validate(Credential{Source: TOKEN_SOURCE_BASIC, UserName: "alice", Password: "secret"}, ConsoleLogger{});
+   if (validator.count("") != 2 || cache.Len() != 2) {
+      failTest(t, "Basic", "2 calls and 2 entries", fmt.Sprintf("%d calls and %d entries", validator.count(""), cache.Len()));
+   }
+}
+
+func TestTokenCacheConcurrent(t *testing.T) {
+   validator := &countingValidator{calls: make(map[string]int)};
+   cache, _ := NewTokenCache(validator.validate, TokenCacheConfig{TTL: time.Minute, MaxEntries: 5});
+
+   var group sync.WaitGroup;
+   for i := 0; i < 20; i++ {
+      group.Add(1);
+      go func(i int) {
+         defer group.Done();
+
+         for j := 0; j < 100; j++ {
+            var token string = fmt.Sprintf("token-%d", (i + j) % 10);
+            principal, err := cache.Validate(Credential{Token: token}, ConsoleLogger{});
+            if (err != nil || principal.UserName != token) {
+               t.Errorf("Concurrent: Expected: %s, Got: %v (%v)", token, principal, err);
+               return;
+            }
+
+            if (j % 25 == 0) {
+               cache.Invalidate(token);
+            }
+
+            if (j == 50 && i == 0) {
+               cache.InvalidateAll();
+            }
+         }
+      }(i);
+   }
+   group.Wait();
+
+   if (cache.Len() > 5) {
+      failTest(t, "Max Entries", 5, cache.Len());
+   }
+}
+
+func TestTokenCacheConfig(t *testing.T) {
+   validator := &countingValidator{calls: make(map[string]int)};
+
+   tests := []struct{
+      title string
+      validator ValidatePrincipal
+      config TokenCacheConfig
+   } {
+      {"No Validator", nil, TokenCacheConfig{TTL: time.Minute}},
+      {"No TTL", validator.validate, TokenCacheConfig{}},
+      {"Negative Revoked TTL", validator.validate, TokenCacheConfig{TTL: time.Minute, RevokedTTL: -time.Second}},
+      {"Negative Max Entries", validator.validate, TokenCacheConfig{TTL: time.Minute, MaxEntries: -1}},
+   };
+
+   for _, test := range(tests) {
+      _, err := NewTokenCache(test.validator, test.config);
+      if (err == nil) {
+         failTest(t, test.title, "an error", nil);
+      }
+   }
+}
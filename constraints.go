@@ -0,0 +1,273 @@
+package goapi;
+
+import (
+   "fmt"
+   "reflect"
+   "regexp"
+   "strings"
+   "time"
+   "unicode/utf8"
+)
+
+// The rules that a param's value can violate (see ParamError).
+const (
+   PARAM_RULE_MIN = "min"
+   PARAM_RULE_MAX = "max"
+   PARAM_RULE_MIN_LENGTH = "minLength"
+   PARAM_RULE_MAX_LENGTH = "maxLength"
+   PARAM_RULE_PATTERN = "pattern"
+   PARAM_RULE_ENUM = "enum"
+   PARAM_RULE_MIN_COUNT = "minCount"
+   PARAM_RULE_MAX_COUNT = "maxCount"
+)
+
+// The types that can use Min and Max.
+var orderedParamTypes map[int]bool = map[int]bool{
+   API_PARAM_TYPE_INT: true,
+   API_PARAM_TYPE_FLOAT: true,
+   API_PARAM_TYPE_INT64: true,
+   API_PARAM_TYPE_UINT: true,
+   API_PARAM_TYPE_TIME: true,
+   API_PARAM_TYPE_DURATION: true,
+};
+
+// Check the constraints on a param for consistency.
+// Will just panic on error.
+func (method ApiMethod) validateConstraints(param ApiMethodParam) {
+   var info paramTypeInfo = paramTypes[param.ParamType];
+
+   if (param.Min != nil || param.Max != nil) {
+      if (!orderedParamTypes[param.ParamType]) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has a min/max, but is not a numeric, time, or duration type", param.Name, method.path));
+      }
+
+      for _, bound := range([]interface{}{param.Min, param.Max}) {
+         if (bound != nil && reflect.TypeOf(bound) != info.goType) {
+            method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has a min/max with the wrong type (%s), must be %s", param.Name, method.path, reflect.TypeOf(bound).String(), info.goType.String()));
+         }
+      }
+
+      if (param.Min != nil && param.Max != nil && compareParamValues(param.Min, param.Max) > 0) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has a min (%s) greater than its max (%s)", param.Name, method.path, formatParamValue(param.Min), formatParamValue(param.Max)));
+      }
+   }
+
+   if (param.MinLength != 0 || param.MaxLength != 0 || param.Pattern != "") {
+      if (param.ParamType != API_PARAM_TYPE_STRING) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has a length or pattern constraint, but is not a string", param.Name, method.path));
+      }
+
+      if (param.MinLength < 0 || param.MaxLength < 0) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has a negative length constraint", param.Name, method.path));
+      }
+
+      if (param.MaxLength != 0 && param.MinLength > param.MaxLength) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has a min length (%d) greater than its max length (%d)", param.Name, method.path, param.MinLength, param.MaxLength));
+      }
+
+      if (param.Pattern != "") {
+         _, err := regexp.Compile(param.Pattern);
+         if (err != nil) {
+            method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has a bad pattern: %v", param.Name, method.path, err));
+         }
+      }
+   }
+
+   if (param.Enum != nil) {
+      if (!isScalarParamType(param.ParamType)) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has an enum, but is a file or JSON", param.Name, method.path));
+      }
+
+      if (len(param.Enum) == 0) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has an empty enum", param.Name, method.path));
+      }
+
+      for _, enumValue := range(param.Enum) {
+         _, err := info.parse(enumValue);
+         if (err != nil) {
+            method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has an enum value ('%s') that is not a %s: %v", param.Name, method.path, enumValue, info.name, err));
+         }
+      }
+   }
+
+   if (param.MinCount != 0 || param.MaxCount != 0) {
+      if (!param.List) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has a count constraint, but is not a list", param.Name, method.path));
+      }
+
+      if (param.MinCount < 0 || param.MaxCount < 0) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has a negative count constraint", param.Name, method.path));
+      }
+
+      if (param.MaxCount != 0 && param.MinCount > param.MaxCount) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has a min count (%d) greater than its max count (%d)", param.Name, method.path, param.MinCount, param.MaxCount));
+      }
+   }
+
+   // The default must follow the rules too.
+   if (param.Default != nil) {
+      var values []interface{} = []interface{}{param.Default};
+      var paramErr *ParamError = nil;
+
+      if (param.List) {
+         values = listValues(param.Default);
+         paramErr = param.checkCount(values);
+      }
+
+      for _, value := range(values) {
+         if (paramErr == nil) {
+            paramErr = param.checkConstraints(value);
+         }
+      }
+
+      if (paramErr != nil) {
+         method.log.Panic(fmt.Sprintf("Param (%s) for API handler (%s) has a default that violates its constraints: %v", param.Name, method.path, paramErr));
+      }
+   }
+}
+
+// Make a copy of |params| with all the constraints ready to use (eg patterns compiled).
+// Assumes the params have already been validated.
+func prepareConstraints(params []ApiMethodParam) []ApiMethodParam {
+   var rtn []ApiMethodParam = make([]ApiMethodParam, len(params));
+
+   for i, param := range(params) {
+      if (param.Pattern != "") {
+         param.pattern = regexp.MustCompile(param.Pattern);
+      }
+
+      if (param.Enum != nil) {
+         param.enumValues = make([]interface{}, 0, len(param.Enum));
+         for _, enumValue := range(param.Enum) {
+            value, _ := paramTypes[param.ParamType].parse(enumValue);
+            param.enumValues = append(param.enumValues, value);
+         }
+      }
+
+      rtn[i] = param;
+   }
+
+   return rtn;
+}
+
+// Check a parsed value against all of the param's constraints.
+// Returns a ParamError on the first violated rule.
+func (param ApiMethodParam) checkConstraints(value interface{}) *ParamError {
+   if (param.Enum != nil && !param.inEnum(value)) {
+      return param.constraintError(PARAM_RULE_ENUM, value, fmt.Sprintf("must be one of: %s", strings.Join(param.Enum, ", ")));
+   }
+
+   if (param.Min != nil && compareParamValues(value, param.Min) < 0) {
+      return param.constraintError(PARAM_RULE_MIN, value, fmt.Sprintf("must be at least %s", formatParamValue(param.Min)));
+   }
+
+   if (param.Max != nil && compareParamValues(value, param.Max) > 0) {
+      return param.constraintError(PARAM_RULE_MAX, value, fmt.Sprintf("must be at most %s", formatParamValue(param.Max)));
+   }
+
+   stringValue, ok := value.(string);
+   if (!ok) {
+      return nil;
+   }
+
+   var length int = utf8.RuneCountInString(stringValue);
+
+   if (param.MinLength != 0 && length < param.MinLength) {
+      return param.constraintError(PARAM_RULE_MIN_LENGTH, value, fmt.Sprintf("must be at least %d characters", param.MinLength));
+   }
+
+   if (param.MaxLength != 0 && length > param.MaxLength) {
+      return param.constraintError(PARAM_RULE_MAX_LENGTH, value, fmt.Sprintf("must be at most %d characters", param.MaxLength));
+   }
+
+   if (param.Pattern != "") {
+      var pattern *regexp.Regexp = param.pattern;
+      if (pattern == nil) {
+         pattern = regexp.MustCompile(param.Pattern);
+      }
+
+      if (!pattern.MatchString(stringValue)) {
+         return param.constraintError(PARAM_RULE_PATTERN, value, fmt.Sprintf("must match the pattern: %s", param.Pattern));
+      }
+   }
+
+   return nil;
+}
+
+// Check the number of values in a list against the param's count constraints.
+func (param ApiMethodParam) checkCount(values interface{}) *ParamError {
+   var count int = reflect.ValueOf(values).Len();
+
+   if (param.MinCount != 0 && count < param.MinCount) {
+      return param.constraintError(PARAM_RULE_MIN_COUNT, values, fmt.Sprintf("must have at least %d values", param.MinCount));
+   }
+
+   if (param.MaxCount != 0 && count > param.MaxCount) {
+      return param.constraintError(PARAM_RULE_MAX_COUNT, values, fmt.Sprintf("must have at most %d values", param.MaxCount));
+   }
+
+   return nil;
+}
+
+func (param ApiMethodParam) inEnum(value interface{}) bool {
+   var enumValues []interface{} = param.enumValues;
+   if (enumValues == nil) {
+      enumValues = prepareConstraints([]ApiMethodParam{param})[0].enumValues;
+   }
+
+   for _, enumValue := range(enumValues) {
+      if (compareParamValues(value, enumValue) == 0) {
+         return true;
+      }
+   }
+
+   return false;
+}
+
+func (param ApiMethodParam) constraintError(rule string, value interface{}, message string) *ParamError {
+   return &ParamError{
+      Param: param.Name,
+      Reason: PARAM_ERROR_CONSTRAINT,
+      Rule: rule,
+      Value: formatParamValue(value),
+      Message: message,
+   };
+}
+
+// Compare two parsed values of the same type.
+// Returns -1, 0, or 1.
+// Unordered types (eg bools) are only ever equal (0) or not (1).
+func compareParamValues(a interface{}, b interface{}) int {
+   switch aValue := a.(type) {
+   case int:
+      return compareOrdered(aValue, b.(int));
+   case int64:
+      return compareOrdered(aValue, b.(int64));
+   case uint:
+      return compareOrdered(aValue, b.(uint));
+   case float64:
+      return compareOrdered(aValue, b.(float64));
+   case time.Duration:
+      return compareOrdered(aValue, b.(time.Duration));
+   case string:
+      return compareOrdered(aValue, b.(string));
+   case time.Time:
+      return aValue.Compare(b.(time.Time));
+   default:
+      if (a == b) {
+         return 0;
+      }
+
+      return 1;
+   }
+}
+
+func compareOrdered[T int | int64 | uint | float64 | time.Duration | string](a T, b T) int {
+   if (a < b) {
+      return -1;
+   } else if (a > b) {
+      return 1;
+   }
+
+   return 0;
+}
@@ -0,0 +1,68 @@
+package goapi;
+
+import (
+   "context"
+   "net/http"
+)
+
+type contextKey int;
+
+const (
+   authContextKey contextKey = iota
+)
+
+// The information about an authenticated request that is carried in the request's context.
+type authContext struct {
+   principal *Principal
+}
+
+func withAuthContext(request *http.Request, principal *Principal) *http.Request {
+   var ctx context.Context = context.WithValue(request.Context(), authContextKey, authContext{principal});
+   return request.WithContext(ctx);
+}
+
+func getAuthContext(ctx context.Context) (authContext, bool) {
+   if (ctx == nil) {
+      return authContext{}, false;
+   }
+
+   auth, ok := ctx.Value(authContextKey).(authContext);
+   if (!ok || auth.principal == nil) {
+      return authContext{&Principal{}}, false;
+   }
+
+   return auth, true;
+}
+
+// Get the principal making an authenticated request.
+// Useful for handlers that do not get implicit params (eg typed handlers).
+// The second return will be false if the request was not authenticated.
+func ContextPrincipal(ctx context.Context) (*Principal, bool) {
+   auth, ok := getAuthContext(ctx);
+   if (!ok) {
+      return nil, false;
+   }
+
+   return auth.principal, true;
+}
+
+// Get the id of the user making an authenticated request.
+// The second return will be false if the request was not authenticated.
+func ContextUserId(ctx context.Context) (UserId, bool) {
+   auth, ok := getAuthContext(ctx);
+   return UserId(auth.principal.UserId), ok;
+}
+
+// Get the name of the user making an authenticated request.
+// The second return will be false if the request was not authenticated.
+func ContextUserName(ctx context.Context) (UserName, bool) {
+   auth, ok := getAuthContext(ctx);
+   return UserName(auth.principal.UserName), ok;
+}
+
+// Get the token of an authenticated request.
+// The second return will be false if the request was not authenticated.
+func ContextToken(ctx context.Context) (Token, bool) {
+   auth, ok := getAuthContext(ctx);
+   return Token(auth.principal.Token), ok;
+}
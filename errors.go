@@ -1,5 +1,11 @@
 package goapi;
 
+import (
+   "errors"
+   "fmt"
+   "strings"
+)
+
 const (
    TOKEN_VALIDATION_NO_TOKEN = iota
    TOKEN_VALIDATION_EXPIRED
@@ -29,6 +35,166 @@ func (err TokenValidationError) Description() string {
    }
 }
 
+// A short, machine-readable name for a TOKEN_VALIDATION_* (or TOKEN_AUTH_*) reason.
+func TokenReasonName(reason int) string {
+   switch reason {
+   case TOKEN_VALIDATION_NO_TOKEN:
+      return "no-token";
+   case TOKEN_VALIDATION_EXPIRED:
+      return "expired";
+   case TOKEN_VALIDATION_REVOKED:
+      return "revoked";
+   case TOKEN_VALIDATION_BAD_SIGNATURE:
+      return "bad-signature";
+   case TOKEN_AUTH_BAD_CREDENTIALS:
+      return "bad-credentials";
+   default:
+      return "unknown";
+   }
+}
+
 func (err TokenValidationError) Error() string {
    return err.Description();
 }
+
+// An authenticated principal is missing some of the roles or scopes that an ApiMethod requires
+// (see ApiMethod.SetRequiredRoles() and ApiMethod.SetRequiredScopes()).
+// The error responder gets these with a 403 (Forbidden).
+type AuthorizationError struct {
+   MissingRoles []string
+   MissingScopes []string
+};
+
+func (err AuthorizationError) Description() string {
+   return "Insufficient permissions";
+}
+
+func (err AuthorizationError) Error() string {
+   return fmt.Sprintf("%s (missing roles: [%s], missing scopes: [%s])", err.Description(), strings.Join(err.MissingRoles, ", "), strings.Join(err.MissingScopes, ", "));
+}
+
+// An error that a handler can return to control the response.
+// The status is used for the response (unless the handler also returns a status)
+// and the code and message are given to the error responder to send to the client.
+// The cause is never sent to the client, it is only logged.
+type APIError struct {
+   // The HTTP status for the response (500 if zero).
+   Status int
+   // A machine-readable code for the error (eg "user_not_found").
+   Code string
+   // A client-safe description of the error.
+   Message string
+   // The internal error (may be nil).
+   Cause error
+};
+
+func (err APIError) Error() string {
+   if (err.Cause == nil) {
+      return fmt.Sprintf("API error (%d, %s): %s", err.Status, err.Code, err.Message);
+   }
+
+   return fmt.Sprintf("API error (%d, %s): %s: %v", err.Status, err.Code, err.Message, err.Cause);
+}
+
+func (err APIError) Unwrap() error {
+   return err.Cause;
+}
+
+// Find an APIError (or *APIError) in |err|'s chain.
+func asAPIError(err error) (APIError, bool) {
+   var apiErr APIError;
+   if (errors.As(err, &apiErr)) {
+      return apiErr, true;
+   }
+
+   var apiErrPointer *APIError;
+   if (errors.As(err, &apiErrPointer) && apiErrPointer != nil) {
+      return *apiErrPointer, true;
+   }
+
+   return APIError{}, false;
+}
+
+// The reasons that a param can fail (see ParamError).
+const (
+   PARAM_ERROR_MISSING = iota
+   PARAM_ERROR_BAD_TYPE
+   PARAM_ERROR_CONSTRAINT
+);
+
+// A param could not be passed to the handler.
+type ParamError struct {
+   // The name of the param.
+   // For a bad element of a list param, this includes the element's index (eg "ids[2]").
+   Param string
+   // Why the param failed (see PARAM_ERROR_*).
+   Reason int
+   // The violated rule (see PARAM_RULE_*), only set for PARAM_ERROR_CONSTRAINT.
+   Rule string
+   // The value that failed (empty for missing params).
+   Value string
+   // A (client-safe) description of the failure.
+   Message string
+};
+
+func (err ParamError) Description() string {
+   switch err.Reason {
+   case PARAM_ERROR_MISSING:
+      return "Required param is missing";
+   case PARAM_ERROR_BAD_TYPE:
+      return "Param has the wrong type";
+   case PARAM_ERROR_CONSTRAINT:
+      return "Param violated a constraint";
+   default:
+      return "Unknown param error";
+   }
+}
+
+// A short, machine-readable name for why the param failed:
+// "missing", "bad-type", or the violated rule (see PARAM_RULE_*) for constraints.
+func (err ParamError) Code() string {
+   switch err.Reason {
+   case PARAM_ERROR_MISSING:
+      return "missing";
+   case PARAM_ERROR_BAD_TYPE:
+      return "bad-type";
+   case PARAM_ERROR_CONSTRAINT:
+      return err.Rule;
+   default:
+      return "unknown";
+   }
+}
+
+func (err ParamError) Error() string {
+   if (err.Reason == PARAM_ERROR_CONSTRAINT) {
+      return fmt.Sprintf("Param (%s) with value '%s' violated rule (%s): %s", err.Param, err.Value, err.Rule, err.Message);
+   }
+
+   if (err.Value != "") {
+      return fmt.Sprintf("%s (%s) with value '%s': %s", err.Description(), err.Param, err.Value, err.Message);
+   }
+
+   return fmt.Sprintf("%s (%s): %s", err.Description(), err.Param, err.Message);
+}
+
+// All of the params that failed for a single request.
+// This is what the ErrorResponder gets when any params fail (use errors.As() to get the first ParamError).
+type ParamErrors []ParamError;
+
+func (errs ParamErrors) Error() string {
+   var messages []string = make([]string, 0, len(errs));
+   for _, err := range(errs) {
+      messages = append(messages, err.Error());
+   }
+
+   return strings.Join(messages, "; ");
+}
+
+func (errs ParamErrors) Unwrap() []error {
+   var rtn []error = make([]error, 0, len(errs));
+   for _, err := range(errs) {
+      rtn = append(rtn, err);
+   }
+
+   return rtn;
+}
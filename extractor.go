@@ -0,0 +1,221 @@
+package goapi;
+
+import (
+   "fmt"
+   "net/http"
+   "strings"
+)
+
+// The sources that the built-in extractors pull credentials from (see Credential.Source).
+const (
+   TOKEN_SOURCE_BEARER = "bearer"
+   TOKEN_SOURCE_PARAM = "param"
+   TOKEN_SOURCE_COOKIE = "cookie"
+   TOKEN_SOURCE_HEADER = "header"
+   TOKEN_SOURCE_BASIC = "basic"
+);
+
+// Token extractors pull a credential out of a request.
+// An ApiMethod tries each of its extractors in order and validates the first credential found.
+// If no extractor finds a credential, the request fails with TOKEN_VALIDATION_NO_TOKEN.
+// When no extractors are set, the original token lookup is used:
+// the "Authorization" header (with any "Bearer" prefix removed) if the header is present at all,
+// otherwise (only if ApiMethod.SetAllowTokenParam(true) was called) the token param.
+// If there is a Basic auth validator, then Basic auth is checked first
+// (and without a token (principal) validator, it is the only default).
+// Set using ApiMethodFactory.SetTokenExtractors() or ApiMethod.SetTokenExtractors().
+type TokenExtractor interface {
+   // The name of the source, this becomes the credential's Source (see TOKEN_SOURCE_*).
+   Source() string
+   // The second return is false if the request does not have a (non-empty) credential in this source.
+   Extract(request *http.Request) (Credential, bool)
+}
+
+type bearerExtractor struct {}
+
+// Tokens in the "Authorization" header (prefixed with "Bearer ").
+// For compatibility, a header without the "Bearer" prefix is also used (unless it is Basic auth).
+func BearerTokenExtractor() TokenExtractor {
+   return bearerExtractor{};
+}
+
+func (extractor bearerExtractor) Source() string {
+   return TOKEN_SOURCE_BEARER;
+}
+
+func (extractor bearerExtractor) Extract(request *http.Request) (Credential, bool) {
+   var header string = strings.TrimSpace(request.Header.Get("Authorization"));
+   if (len(header) >= 6 && strings.EqualFold(header[0:6], "Basic ")) {
+      return Credential{}, false;
+   }
+
+   return tokenCredential(strings.TrimPrefix(header, "Bearer"), TOKEN_SOURCE_BEARER);
+}
+
+type paramExtractor struct {}
+
+// Tokens in the "token" query/form param.
+func ParamTokenExtractor() TokenExtractor {
+   return paramExtractor{};
+}
+
+func (extractor paramExtractor) Source() string {
+   return TOKEN_SOURCE_PARAM;
+}
+
+func (extractor paramExtractor) Extract(request *http.Request) (Credential, bool) {
+   request.ParseMultipartForm(MULTIPART_PARSE_SIZE);
+   return tokenCredential(request.FormValue(PARAM_TOKEN), TOKEN_SOURCE_PARAM);
+}
+
+type cookieExtractor struct {
+   name string
+}
+
+// Tokens in the cookie named |name|.
+func CookieTokenExtractor(name string) TokenExtractor {
+   return cookieExtractor{name};
+}
+
+func (extractor cookieExtractor) Source() string {
+   return TOKEN_SOURCE_COOKIE;
+}
+
+func (extractor cookieExtractor) Extract(request *http.Request) (Credential, bool) {
+   cookie, err := request.Cookie(extractor.name);
+   if (err != nil) {
+      return Credential{}, false;
+   }
+
+   return tokenCredential(cookie.Value, TOKEN_SOURCE_COOKIE);
+}
+
+type headerExtractor struct {
+   name string
+}
+
+// Tokens in the (non-standard) header named |name| (eg "X-API-Key").
+func HeaderTokenExtractor(name string) TokenExtractor {
+   return headerExtractor{name};
+}
+
+func (extractor headerExtractor) Source() string {
+   return TOKEN_SOURCE_HEADER;
+}
+
+func (extractor headerExtractor) Extract(request *http.Request) (Credential, bool) {
+   return tokenCredential(request.Header.Get(extractor.name), TOKEN_SOURCE_HEADER);
+}
+
+type basicExtractor struct {}
+
+// A user name and password using HTTP Basic authentication.
+// The credential will have no token.
+func BasicAuthExtractor() TokenExtractor {
+   return basicExtractor{};
+}
+
+func (extractor basicExtractor) Source() string {
+   return TOKEN_SOURCE_BASIC;
+}
+
+func (extractor basicExtractor) Extract(request *http.Request) (Credential, bool) {
+   userName, password, ok := request.BasicAuth();
+   if (!ok || userName == "") {
+      return Credential{}, false;
+   }
+
+   return Credential{Source: TOKEN_SOURCE_BASIC, UserName: userName, Password: password}, true;
+}
+
+// The token lookup from before extractors (see getTokenExtractors()).
+type legacyExtractor struct {
+   allowTokenParam bool
+}
+
+func (extractor legacyExtractor) Source() string {
+   return TOKEN_SOURCE_BEARER;
+}
+
+func (extractor legacyExtractor) Extract(request *http.Request) (Credential, bool) {
+   // Any Authorization header (even an empty or Basic one) is the token, the param is only checked without one.
+   authHeader, ok := request.Header["Authorization"];
+   if (ok) {
+      return tokenCredential(strings.TrimPrefix(strings.TrimSpace(authHeader[0]), "Bearer"), TOKEN_SOURCE_BEARER);
+   }
+
+   if (!extractor.allowTokenParam) {
+      return Credential{}, false;
+   }
+
+   request.ParseMultipartForm(MULTIPART_PARSE_SIZE);
+   return tokenCredential(request.FormValue(PARAM_TOKEN), TOKEN_SOURCE_PARAM);
+}
+
+func tokenCredential(text string, source string) (Credential, bool) {
+   var token string = strings.TrimSpace(text);
+   if (token == "") {
+      return Credential{}, false;
+   }
+
+   return Credential{Token: token, Source: source}, true;
+}
+
+// Returns this so you can chain.
+// Overrides the factory's extractors (see ApiMethodFactory.SetTokenExtractors()).
+// Calling with no extractors goes back to the defaults.
+func (method *ApiMethod) SetTokenExtractors(extractors ...TokenExtractor) *ApiMethod {
+   method.tokenExtractors = checkTokenExtractors(method.log, fmt.Sprintf("API method for [%s]", method.path), extractors);
+   return method;
+}
+
+// Copy |extractors| (nil if there are none) and panic if any are nil.
+func checkTokenExtractors(log Logger, owner string, extractors []TokenExtractor) []TokenExtractor {
+   if (len(extractors) == 0) {
+      return nil;
+   }
+
+   for _, extractor := range(extractors) {
+      if (extractor == nil) {
+         log.Panic(fmt.Sprintf("%s has a nil token extractor", owner));
+      }
+   }
+
+   return append([]TokenExtractor{}, extractors...);
+}
+
+func (method ApiMethod) getTokenExtractors() []TokenExtractor {
+   if (method.tokenExtractors != nil) {
+      return method.tokenExtractors;
+   }
+
+   var extractors []TokenExtractor = make([]TokenExtractor, 0, 2);
+
+   // Basic auth goes first, otherwise the legacy lookup would take the header as a token.
+   if (method.basicAuthValidator != nil) {
+      extractors = append(extractors, BasicAuthExtractor());
+   }
+
+   if (method.principalValidator != nil) {
+      extractors = append(extractors, legacyExtractor{method.allowTokenParam});
+   }
+
+   return extractors;
+}
+
+// Get the first credential that any of the extractors can find.
+// Credentials without a source get the extractor's.
+func extractCredential(request *http.Request, extractors []TokenExtractor) (Credential, bool) {
+   for _, extractor := range(extractors) {
+      credential, ok := extractor.Extract(request);
+      if (ok) {
+         if (credential.Source == "") {
+            credential.Source = extractor.Source();
+         }
+
+         return credential, true;
+      }
+   }
+
+   return Credential{}, false;
+}
@@ -0,0 +1,185 @@
+package goapi;
+
+import (
+   "net/http"
+   "net/http/httptest"
+   "strings"
+   "testing"
+);
+
+func TestBearerTokenExtractor(t *testing.T) {
+   tests := []struct{
+      title string
+      authHeaderKey string
+      authHeaderValue string
+      token string
+      ok bool
+   } {
+      {
+         title: "Basic",
+         authHeaderKey: "Authorization",
+         authHeaderValue: "Bearer TOKEN",
+         token: "TOKEN",
+         ok: true,
+      },
+      {
+         title: "Bad Header",
+         authHeaderKey: "BadHeader",
+         authHeaderValue: "Bearer TOKEN",
+         token: "TOKEN",
+         ok: false,
+      },
+      {
+         title: "Empty Value",
+         authHeaderKey: "Authorization",
+         authHeaderValue: "",
+         token: "",
+         ok: false,
+      },
+      {
+         title: "Empty Token",
+         authHeaderKey: "Authorization",
+         authHeaderValue: "Bearer ",
+         token: "",
+         ok: false,
+      },
+      {
+         title: "Whitspace Token",
+         authHeaderKey: "Authorization",
+         authHeaderValue: "Bearer            ",
+         token: "",
+         ok: false,
+      },
+      {
+         title: "Trim Token",
+         authHeaderKey: "Authorization",
+         authHeaderValue: "     Bearer TOKEN    ",
+         token: "TOKEN",
+         ok: true,
+      },
+      {
+         title: "Spaced Token",
+         authHeaderKey: "Authorization",
+         authHeaderValue: "Bearer T O K E N",
+         token: "T O K E N",
+         ok: true,
+      },
+      {
+         title: "Base64 Token",
+         authHeaderKey: "Authorization",
+         authHeaderValue: "Bearer ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/=",
+         token: "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/=",
+         ok: true,
+      },
+   };
+
+   for _, test := range(tests) {
+      request, err := http.NewRequest("GET", "http://example.com", nil);
+      if (err != nil) {
+         t.Error("Failed to create a request: ", err);
+      }
+
+      request.Header.Set(test.authHeaderKey, test.authHeaderValue);
+      credential, ok := extractCredential(request, []TokenExtractor{BearerTokenExtractor()});
+      token := credential.Token;
+
+      if (ok != test.ok) {
+         failTest(t, test.title, test.ok, ok);
+         continue;
+      }
+
+      if (!ok) {
+         continue;
+      }
+
+      if (token != test.token) {
+         failTest(t, test.title, test.token, token);
+         continue;
+      }
+   }
+}
+
+func TestTokenExtractors(t *testing.T) {
+   factory := ApiMethodFactory{};
+   factory.SetPrincipalValidator(func(credential Credential, log Logger) (*Principal, error) {
+      return &Principal{UserName: credential.Source + " " + credential.Token + credential.UserName + credential.Password}, nil;
+   });
+
+   handler := func(userName UserName) interface{} {
+      return userName;
+   };
+
+   defaults := factory.NewApiMethod("/defaults", handler, true, []ApiMethodParam{});
+   param := factory.NewApiMethod("/param", handler, true, []ApiMethodParam{}).SetAllowTokenParam(true);
+   all := factory.NewApiMethod("/all", handler, true, []ApiMethodParam{}).SetTokenExtractors(
+      HeaderTokenExtractor("X-API-Key"),
+      CookieTokenExtractor("session"),
+      BasicAuthExtractor(),
+      BearerTokenExtractor(),
+      ParamTokenExtractor(),
+   );
+
+   factory.SetTokenExtractors(CookieTokenExtractor("session"));
+   cookie := factory.NewApiMethod("/cookie", handler, true, []ApiMethodParam{});
+   reset := factory.NewApiMethod("/reset", handler, true, []ApiMethodParam{}).SetTokenExtractors();
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+      query string
+      headers map[string]string
+      cookie string
+      status int
+      response string
+   } {
+      {"Default Bearer", defaults, "", map[string]string{"Authorization": "Bearer abc"}, "", http.StatusOK, `"bearer abc"`},
+      {"Default No Param", defaults, "token=abc", nil, "", http.StatusUnauthorized, `{"Success":false,"Code":401}`},
+      {"Default Basic As Token", defaults, "", map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, "", http.StatusOK, `"bearer Basic dXNlcjpwYXNz"`},
+      {"Default No Prefix", defaults, "", map[string]string{"Authorization": "abc"}, "", http.StatusOK, `"bearer abc"`},
+      {"Param", param, "token=abc", nil, "", http.StatusOK, `"param abc"`},
+      {"Param After Bearer", param, "token=abc", map[string]string{"Authorization": "Bearer def"}, "", http.StatusOK, `"bearer def"`},
+      {"Empty Bearer Blocks Param", param, "token=abc", map[string]string{"Authorization": "Bearer "}, "", http.StatusUnauthorized, `{"Success":false,"Code":401}`},
+      {"Empty Header Blocks Param", param, "token=abc", map[string]string{"Authorization": ""}, "", http.StatusUnauthorized, `{"Success":false,"Code":401}`},
+      {"API Key First", all, "token=abc", map[string]string{"X-API-Key": "key", "Authorization": "Bearer def"}, "ses", http.StatusOK, `"header key"`},
+      {"Cookie Second", all, "token=abc", map[string]string{"Authorization": "Bearer def"}, "ses", http.StatusOK, `"cookie ses"`},
+      {"Basic Without Validator", all, "", map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, "", http.StatusUnauthorized, `{"Success":false,"Code":401}`},
+      {"Bearer", all, "token=abc", map[string]string{"Authorization": "Bearer def"}, "", http.StatusOK, `"bearer def"`},
+      {"None", all, "", nil, "", http.StatusUnauthorized, `{"Success":false,"Code":401}`},
+      {"Factory Cookie", cookie, "", map[string]string{"Authorization": "Bearer def"}, "ses", http.StatusOK, `"cookie ses"`},
+      {"Factory Cookie Only", cookie, "", map[string]string{"Authorization": "Bearer def"}, "", http.StatusUnauthorized, `{"Success":false,"Code":401}`},
+      {"Reset", reset, "", map[string]string{"Authorization": "Bearer def"}, "ses", http.StatusOK, `"bearer def"`},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodGet, "/?" + test.query, nil);
+      for key, value := range(test.headers) {
+         request.Header.Set(key, value);
+      }
+
+      if (test.cookie != "") {
+         request.AddCookie(&http.Cookie{Name: "session", Value: test.cookie});
+      }
+
+      response := httptest.NewRecorder();
+      test.method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title + " (status)", test.status, response.Code);
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
+func TestTokenExtractorsValidation(t *testing.T) {
+   defer func() {
+      if (recover() == nil) {
+         t.Errorf("Nil Extractor: Failed to Panic");
+      }
+   }();
+
+   factory := ApiMethodFactory{};
+   factory.SetTokenExtractors(BearerTokenExtractor(), nil);
+}
@@ -0,0 +1,150 @@
+package goapi;
+
+import (
+   "fmt"
+   "net/http"
+   "reflect"
+   "runtime/debug"
+)
+
+// Injectors supply custom implicit parameters (eg a *sql.Tx, the current tenant, or a request-scoped logger).
+// Once an injector is registered for a type, any handler can take that type (anywhere in its arguments)
+// and the injector will be called for every request to build the value.
+// Injectors are only called once all the (explicit) params are valid, in the order that the handler takes them.
+// If an injector fails, the handler is not called and the error is handled like an error returned from the handler
+// (so return an APIError to control the response).
+// Typed handlers cannot take injected values, so NewTypedApiMethod() will panic if the factory has any injectors.
+type injector struct {
+   inject func(request *http.Request) (reflect.Value, error)
+   cleanup func(value reflect.Value, err error)
+}
+
+// Register an injector for values of type T.
+// |cleanup| is optional (may be nil), it is called once the response has been written (including streamed responses)
+// with the injected value and the handler's error (or the error that stopped the handler from being called, or a PanicError).
+// Handlers that finish after their timeout (see ApiMethod.SetTimeout()) give the context's error instead.
+// This is the place to commit/rollback transactions or close resources.
+// Cleanups are called in the reverse order of injection.
+// Registering a type again will replace its injector.
+// Will panic if T is already a param or implicit param type.
+func RegisterInjector[T any](factory *ApiMethodFactory, inject func(request *http.Request) (T, error), cleanup func(value T, err error)) {
+   var valueType reflect.Type = reflect.TypeOf((*T)(nil)).Elem();
+
+   var log Logger = factory.log;
+   if (log == nil) {
+      log = ConsoleLogger{};
+   }
+
+   if (inject == nil) {
+      log.Panic(fmt.Sprintf("Nil injector for type (%s)", valueType.String()));
+   }
+
+   if (isImplicitType(valueType)) {
+      log.Panic(fmt.Sprintf("Cannot register an injector for a built-in implicit param type (%s)", valueType.String()));
+   }
+
+   if (isParamGoType(valueType)) {
+      log.Panic(fmt.Sprintf("Cannot register an injector for a param type (%s)", valueType.String()));
+   }
+
+   var rtn injector = injector{
+      inject: func(request *http.Request) (reflect.Value, error) {
+         value, err := inject(request);
+         if (err != nil) {
+            return reflect.Value{}, err;
+         }
+
+         // Use the exact type (in case T is an interface).
+         var rtn reflect.Value = reflect.New(valueType).Elem();
+         rtn.Set(reflect.ValueOf(&value).Elem());
+         return rtn, nil;
+      },
+   };
+
+   if (cleanup != nil) {
+      rtn.cleanup = func(value reflect.Value, err error) {
+         // Nil interfaces cannot be asserted, but the zero value is the same thing.
+         typedValue, _ := value.Interface().(T);
+         cleanup(typedValue, err);
+      };
+   }
+
+   // Methods that were already built keep their own copy.
+   var injectors map[reflect.Type]injector = make(map[reflect.Type]injector);
+   for existingType, existing := range(factory.injectors) {
+      injectors[existingType] = existing;
+   }
+   injectors[valueType] = rtn;
+
+   factory.injectors = injectors;
+}
+
+// Could |valueType| be used by a handler for an (explicit) param.
+func isParamGoType(valueType reflect.Type) bool {
+   if (valueType.Kind() == reflect.Pointer || valueType.Kind() == reflect.Slice) {
+      paramType, ok := paramTypeForGoType(valueType.Elem());
+      return ok && isScalarParamType(paramType);
+   }
+
+   _, ok := paramTypeForGoType(valueType);
+   return ok;
+}
+
+// Build all the injected values for a handler (the slots in |args| for injected types will be filled in).
+// The returned cleanup function should always be called once the handler is done (it is never nil).
+// If an injector fails, then the values that were already injected are cleaned up with its error.
+func (method ApiMethod) injectArguments(args []reflect.Value, request *http.Request) (func(err error), error) {
+   var handlerType reflect.Type = reflect.TypeOf(method.handler);
+   var cleanups []func(err error) = make([]func(err error), 0);
+
+   var cleanup func(err error) = func(err error) {
+      for i := len(cleanups) - 1; i >= 0; i-- {
+         cleanups[i](err);
+      }
+   };
+
+   for i := 0; i < handlerType.NumIn(); i++ {
+      injector, ok := method.injectors[handlerType.In(i)];
+      if (!ok || isImplicitType(handlerType.In(i))) {
+         continue;
+      }
+
+      value, err := injector.inject(request);
+      if (err != nil) {
+         method.log.WarnE(fmt.Sprintf("Injector for type (%s) failed for API handler (%s)", handlerType.In(i).String(), method.path), err);
+         cleanup(err);
+         return noCleanup, err;
+      }
+
+      args[i] = value;
+      if (injector.cleanup != nil) {
+         cleanups = append(cleanups, func(err error) {
+            injector.cleanup(value, err);
+         });
+      }
+   }
+
+   return cleanup, nil;
+}
+
+// Call the handler with all its injected values.
+// Returns the cleanup for the injected values (never nil), which is called here only if the handler panics.
+func (method ApiMethod) callWithInjectors(args []reflect.Value, request *http.Request) (interface{}, int, string, func(err error), error) {
+   cleanup, err := method.injectArguments(args, request);
+   if (err != nil) {
+      return nil, 0, method.contentType, noCleanup, err;
+   }
+
+   defer func() {
+      recovered := recover();
+      if (recovered != nil) {
+         cleanup(PanicError{recovered, debug.Stack()});
+         panic(recovered);
+      }
+   }();
+
+   responseObj, httpStatus, contentType, err := method.createReturnValues(reflect.ValueOf(method.handler).Call(args));
+   return responseObj, httpStatus, contentType, cleanup, err;
+}
+
+func noCleanup(err error) {}
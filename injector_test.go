@@ -0,0 +1,249 @@
+package goapi;
+
+import (
+   "context"
+   "errors"
+   "fmt"
+   "io"
+   "net/http"
+   "net/http/httptest"
+   "strings"
+   "testing"
+   "time"
+);
+
+type testTenant struct {
+   Name string
+}
+
+type testTx struct {
+   Id int
+}
+
+func TestInjectors(t *testing.T) {
+   var events []string = nil;
+   var nextTx int = 0;
+
+   factory := ApiMethodFactory{};
+   RegisterInjector(&factory, func(request *http.Request) (*testTenant, error) {
+      var name string = request.Header.Get("X-Tenant");
+      if (name == "") {
+         return nil, APIError{Status: http.StatusForbidden, Code: "no_tenant", Message: "No tenant"};
+      }
+
+      events = append(events, "tenant");
+      return &testTenant{name}, nil;
+   }, nil);
+   RegisterInjector(&factory, func(request *http.Request) (*testTx, error) {
+      nextTx++;
+      events = append(events, fmt.Sprintf("begin %d", nextTx));
+      return &testTx{nextTx}, nil;
+   }, func(tx *testTx, err error) {
+      if (err != nil) {
+         events = append(events, fmt.Sprintf("rollback %d", tx.Id));
+      } else {
+         events = append(events, fmt.Sprintf("commit %d", tx.Id));
+      }
+   });
+   RegisterInjector(&factory, func(request *http.Request) (Logger, error) {
+      return nil, nil;
+   }, nil);
+
+   method := factory.NewApiMethod("/inject", func(tx *testTx, someInt int, tenant *testTenant, log Logger) (interface{}, error) {
+      if (someInt < 0) {
+         return nil, fmt.Errorf("Negative");
+      }
+
+      if (someInt == 0) {
+         panic("boom");
+      }
+
+      return fmt.Sprintf("%s %d %d %v", tenant.Name, tx.Id, someInt, log == nil), nil;
+   }, false, []ApiMethodParam{
+      ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT, Required: true},
+   });
+
+   tests := []struct{
+      title string
+      query string
+      tenant string
+      status int
+      response string
+      events string
+   } {
+      {"Basic", "someInt=1", "acme", http.StatusOK, `"acme 1 1 true"`, "begin 1, tenant, commit 1"},
+      {"Handler Error", "someInt=-1", "acme", http.StatusInternalServerError, `{"Success":false,"Code":500}`, "begin 2, tenant, rollback 2"},
+      {"Panic", "someInt=0", "acme", http.StatusInternalServerError, `{"Success":false,"Code":500}`, "begin 3, tenant, rollback 3"},
+      {"Injector Error", "someInt=1", "", http.StatusForbidden, `{"Success":false,"Code":403,"ErrorCode":"no_tenant","Message":"No tenant"}`, "begin 4, rollback 4"},
+      {"Bad Param", "someInt=a", "acme", http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"someInt","Code":"bad-type","Message":"must be a int"}]}`, ""},
+   };
+
+   for _, test := range(tests) {
+      events = nil;
+
+      request := httptest.NewRequest(http.MethodGet, "/inject?" + test.query, nil);
+      request.Header.Set("X-Tenant", test.tenant);
+
+      response := httptest.NewRecorder();
+      method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title + " (status)", test.status, response.Code);
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+
+      if (strings.Join(events, ", ") != test.events) {
+         failTest(t, test.title + " (events)", test.events, strings.Join(events, ", "));
+      }
+   }
+}
+
+func TestInjectorCleanupErrors(t *testing.T) {
+   var cleanupErr error = nil;
+
+   factory := ApiMethodFactory{};
+   RegisterInjector(&factory, func(request *http.Request) (*testTx, error) {
+      return &testTx{1}, nil;
+   }, func(tx *testTx, err error) {
+      cleanupErr = err;
+   });
+
+   method := factory.NewApiMethod("/inject", func(tx *testTx) { panic("boom"); }, false, []ApiMethodParam{});
+   method.Middleware()(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/inject", nil));
+
+   var panicErr PanicError;
+   if (!errors.As(cleanupErr, &panicErr) || panicErr.Value != "boom") {
+      failTest(t, "Panic Cleanup", "a PanicError", cleanupErr);
+   }
+}
+
+type testEventReader struct {
+   reader io.Reader
+   events *[]string
+}
+
+func (reader testEventReader) Read(data []byte) (int, error) {
+   *reader.events = append(*reader.events, "read");
+   return reader.reader.Read(data);
+}
+
+// Streamed responses may still need the injected values, so cleanup waits for the response.
+func TestInjectorCleanupAfterResponse(t *testing.T) {
+   var events []string = nil;
+
+   factory := ApiMethodFactory{};
+   RegisterInjector(&factory, func(request *http.Request) (*testTx, error) {
+      events = append(events, "begin");
+      return &testTx{1}, nil;
+   }, func(tx *testTx, err error) {
+      events = append(events, "commit");
+   });
+
+   method := factory.NewApiMethod("/stream", func(tx *testTx) interface{} {
+      return testEventReader{strings.NewReader("streamed"), &events};
+   }, false, []ApiMethodParam{});
+
+   response := httptest.NewRecorder();
+   method.Middleware()(response, httptest.NewRequest(http.MethodGet, "/stream", nil));
+
+   if (response.Body.String() != "streamed") {
+      failTest(t, "Stream", "streamed", response.Body.String());
+   }
+
+   var expected string = "begin, read, read, commit";
+   if (strings.Join(events, ", ") != expected) {
+      failTest(t, "Stream (events)", expected, strings.Join(events, ", "));
+   }
+}
+
+// A handler that finishes after its timeout already had a 503 sent, so its cleanup should not see success.
+func TestInjectorTimeout(t *testing.T) {
+   var cleanupErrs chan error = make(chan error, 1);
+
+   factory := ApiMethodFactory{};
+   factory.SetLogger(quietLogger{});
+   RegisterInjector(&factory, func(request *http.Request) (*testTx, error) {
+      return &testTx{1}, nil;
+   }, func(tx *testTx, err error) {
+      cleanupErrs <- err;
+   });
+
+   method := factory.NewApiMethod("/slow", func(ctx context.Context, tx *testTx) error {
+      <-ctx.Done();
+      time.Sleep(10 * time.Millisecond);
+      return nil;
+   }, false, []ApiMethodParam{}).SetTimeout(20 * time.Millisecond);
+
+   response := httptest.NewRecorder();
+   method.Middleware()(response, httptest.NewRequest(http.MethodGet, "/slow", nil));
+
+   if (response.Code != http.StatusServiceUnavailable) {
+      failTest(t, "Timeout (status)", http.StatusServiceUnavailable, response.Code);
+   }
+
+   select {
+   case err := <-cleanupErrs:
+      if (!errors.Is(err, context.DeadlineExceeded)) {
+         failTest(t, "Timeout (cleanup)", context.DeadlineExceeded, err);
+      }
+   case <-time.After(time.Second):
+      t.Errorf("Timeout: Cleanup was never called");
+   }
+}
+
+func TestInjectorValidation(t *testing.T) {
+   tests := []struct{
+      title string
+      register func(factory *ApiMethodFactory)
+   } {
+      {"Param Type", func(factory *ApiMethodFactory) {
+         RegisterInjector(factory, func(request *http.Request) (int, error) { return 0, nil; }, nil);
+      }},
+      {"Pointer Param Type", func(factory *ApiMethodFactory) {
+         RegisterInjector(factory, func(request *http.Request) (*string, error) { return nil, nil; }, nil);
+      }},
+      {"List Param Type", func(factory *ApiMethodFactory) {
+         RegisterInjector(factory, func(request *http.Request) ([]int, error) { return nil, nil; }, nil);
+      }},
+      {"Implicit Type", func(factory *ApiMethodFactory) {
+         RegisterInjector(factory, func(request *http.Request) (UserId, error) { return 0, nil; }, nil);
+      }},
+      {"Nil Injector", func(factory *ApiMethodFactory) {
+         RegisterInjector[*testTx](factory, nil, nil);
+      }},
+      {"Typed Handler", func(factory *ApiMethodFactory) {
+         RegisterInjector(factory, func(request *http.Request) (*testTx, error) { return nil, nil; }, nil);
+         NewTypedApiMethod(*factory, "/typed", false, func(ctx context.Context, request struct{}) (interface{}, error) {
+            return nil, nil;
+         });
+      }},
+   };
+
+   for _, test := range(tests) {
+      func() {
+         defer func() {
+            if (recover() == nil) {
+               t.Errorf("%s: Failed to Panic", test.title);
+            }
+         }();
+
+         factory := ApiMethodFactory{};
+         test.register(&factory);
+      }();
+   }
+
+   // Handlers cannot take types without an injector.
+   func() {
+      defer func() {
+         if (recover() == nil) {
+            t.Errorf("Unregistered Type: Failed to Panic");
+         }
+      }();
+
+      factory := ApiMethodFactory{};
+      factory.NewApiMethod("/inject", func(tx *testTx) {}, false, []ApiMethodParam{});
+   }();
+}
@@ -0,0 +1,216 @@
+package goapi;
+
+import (
+   "bytes"
+   "encoding/json"
+   "errors"
+   "fmt"
+   "io"
+   "mime"
+   "net/http"
+   "reflect"
+   "strings"
+)
+
+// The most bytes of a JSON body that will be read (unless changed with ApiMethodFactory.SetMaxBodySize()).
+// Larger bodies get a 413 (Request Entity Too Large).
+const DEFAULT_MAX_BODY_SIZE = 4 * 1024 * 1024
+
+// The JSON body of a request.
+// The body is only read (and decoded) once per request, no matter how many params use it.
+type jsonBody struct {
+   request *http.Request
+   // Zero means no limit.
+   maxSize int64
+   loaded bool
+   raw []byte
+   fields map[string]json.RawMessage
+   err error
+   // A bad body is only reported once, no matter how many params use it.
+   badJSONReported bool
+}
+
+func (method ApiMethod) newJSONBody(request *http.Request) *jsonBody {
+   return &jsonBody{request: request, maxSize: method.maxBodySize};
+}
+
+// The errors for |param| failing because the body is bad.
+// Only the first param gets an error, the rest get an empty (but non-nil) ParamErrors.
+func (body *jsonBody) badJSONErrors(param ApiMethodParam) ParamErrors {
+   if (body.badJSONReported) {
+      return ParamErrors{};
+   }
+
+   body.badJSONReported = true;
+   return ParamErrors{*param.badJSONError()};
+}
+
+// Does the request claim to have a JSON body (application/json or any "+json" type).
+func isJSONRequest(request *http.Request) bool {
+   mediaType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type"));
+   if (err != nil) {
+      return false;
+   }
+
+   return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json");
+}
+
+// Read the body.
+// The request's body will be replaced so the handler can still read it.
+func (body *jsonBody) load() error {
+   if (body.loaded) {
+      return body.err;
+   }
+   body.loaded = true;
+
+   if (body.request.Body == nil) {
+      return nil;
+   }
+
+   var reader io.Reader = body.request.Body;
+   if (body.maxSize > 0) {
+      reader = http.MaxBytesReader(nil, body.request.Body, body.maxSize);
+   }
+
+   body.raw, body.err = io.ReadAll(reader);
+   body.request.Body.Close();
+   body.request.Body = io.NopCloser(bytes.NewReader(body.raw));
+
+   if (body.err != nil) {
+      body.err = fmt.Errorf("Unable to read JSON request body: %w", body.err);
+   }
+
+   return body.err;
+}
+
+// Was the body larger than the limit.
+// Requests with bodies that are too large should get a 413 (see bodyTooLargeError()).
+func (body *jsonBody) tooLarge() bool {
+   var maxBytesErr *http.MaxBytesError;
+   return errors.As(body.err, &maxBytesErr);
+}
+
+func (body *jsonBody) empty() bool {
+   return len(bytes.TrimSpace(body.raw)) == 0;
+}
+
+// Get the string value of a top-level field in the body (and whether the field exists).
+// Strings are unquoted, null is treated as missing, and all other values are passed along as raw JSON.
+func (body *jsonBody) field(name string) (string, bool, error) {
+   raw, ok, err := body.rawField(name);
+   if (err != nil || !ok) {
+      return "", false, err;
+   }
+
+   return jsonText(name, raw);
+}
+
+// Get the string values of a top-level field in the body.
+// An array gives one value per element (each converted like field()), anything else gives a single value.
+func (body *jsonBody) fieldValues(name string) ([]string, error) {
+   raw, ok, err := body.rawField(name);
+   if (err != nil || !ok) {
+      return nil, err;
+   }
+
+   if (!strings.HasPrefix(strings.TrimSpace(string(raw)), "[")) {
+      value, ok, err := jsonText(name, raw);
+      if (err != nil || !ok) {
+         return nil, err;
+      }
+
+      return []string{value}, nil;
+   }
+
+   var elements []json.RawMessage;
+   err = json.Unmarshal(raw, &elements);
+   if (err != nil) {
+      return nil, fmt.Errorf("Unable to decode JSON request body field (%s): %w", name, err);
+   }
+
+   var values []string = make([]string, 0, len(elements));
+   for _, element := range(elements) {
+      value, ok, err := jsonText(name, element);
+      if (err != nil) {
+         return nil, err;
+      }
+
+      if (ok) {
+         values = append(values, value);
+      }
+   }
+
+   return values, nil;
+}
+
+func (body *jsonBody) rawField(name string) (json.RawMessage, bool, error) {
+   err := body.load();
+   if (err != nil) {
+      return nil, false, err;
+   }
+
+   if (body.empty()) {
+      return nil, false, nil;
+   }
+
+   if (body.fields == nil) {
+      err = json.Unmarshal(body.raw, &body.fields);
+      if (err != nil) {
+         body.err = fmt.Errorf("Unable to decode JSON request body: %w", err);
+         return nil, false, body.err;
+      }
+   }
+
+   raw, ok := body.fields[name];
+   return raw, ok, nil;
+}
+
+// Convert a raw JSON value into a param's string value.
+// Strings are unquoted, null is treated as missing, and all other values are passed along as raw JSON.
+func jsonText(name string, raw json.RawMessage) (string, bool, error) {
+   var text string = strings.TrimSpace(string(raw));
+   if (text == "null") {
+      return "", false, nil;
+   }
+
+   if (strings.HasPrefix(text, "\"")) {
+      var value string;
+      err := json.Unmarshal(raw, &value);
+      if (err != nil) {
+         return "", false, fmt.Errorf("Unable to decode JSON request body field (%s): %w", name, err);
+      }
+
+      return value, true, nil;
+   }
+
+   return text, true, nil;
+}
+
+// Decode the entire body into a new value of |valueType|.
+// Returns false if the body is empty.
+func (body *jsonBody) decode(valueType reflect.Type) (bool, reflect.Value, error) {
+   var value reflect.Value = reflect.New(valueType);
+
+   ok, err := body.decodeInto(value.Interface());
+   return ok, value.Elem(), err;
+}
+
+// Decode the entire body into |target| (a pointer).
+// Returns false if the body is empty.
+func (body *jsonBody) decodeInto(target interface{}) (bool, error) {
+   err := body.load();
+   if (err != nil) {
+      return false, err;
+   }
+
+   if (body.empty()) {
+      return false, nil;
+   }
+
+   err = json.Unmarshal(body.raw, target);
+   if (err != nil) {
+      return false, fmt.Errorf("Unable to decode JSON request body into %s: %w", reflect.TypeOf(target).Elem().String(), err);
+   }
+
+   return true, nil;
+}
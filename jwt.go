@@ -0,0 +1,280 @@
+package goapi;
+
+import (
+   "bytes"
+   "crypto/hmac"
+   "crypto/sha256"
+   "crypto/sha512"
+   "encoding/base64"
+   "encoding/json"
+   "fmt"
+   "hash"
+   "math"
+   "strconv"
+   "strings"
+   "time"
+)
+
+const (
+   JWT_ALGORITHM_HS256 = "HS256"
+   JWT_ALGORITHM_HS384 = "HS384"
+   JWT_ALGORITHM_HS512 = "HS512"
+);
+
+var jwtHashes map[string]func() hash.Hash = map[string]func() hash.Hash{
+   JWT_ALGORITHM_HS256: sha256.New,
+   JWT_ALGORITHM_HS384: sha512.New384,
+   JWT_ALGORITHM_HS512: sha512.New,
+};
+
+// The configuration for a JWT validator (see NewJWTValidator()).
+type JWTConfig struct {
+   // The shared HMAC secret (required).
+   Secret []byte
+   // The algorithms that tokens may be signed with (see JWT_ALGORITHM_*).
+   // Defaults to all of them.
+   Algorithms []string
+   // If set, the "iss" claim must match exactly.
+   Issuer string
+   // If set, the "aud" claim (a string or list of strings) must contain this.
+   Audience string
+   // How far off the "exp" and "nbf" claims may be from the current time.
+   ClockSkew time.Duration
+   // The claim that holds the user's (integer) id, defaults to "sub".
+   // The claim may be a number or a string holding an integer.
+   UserIdClaim string
+   // The claim that holds the user's name, defaults to "name".
+   // Tokens without this claim will have an empty name.
+   UserNameClaim string
+   // The current time, defaults to time.Now (useful for testing).
+   Now func() time.Time
+}
+
+// Make a ValidatePrincipal that validates HMAC signed JSON Web Tokens (RFC 7519).
+// Tokens that are malformed, have a bad signature, use an algorithm that is not allowed,
+// have the wrong issuer or audience, or lack a usable user id claim fail with TOKEN_VALIDATION_BAD_SIGNATURE.
+// Tokens that are past their "exp" or not yet at their "nbf" fail with TOKEN_VALIDATION_EXPIRED.
+// All the token's claims are put into Principal.Claims (numbers are json.Numbers)
+// and the "exp" claim (if any) becomes Principal.ExpiresAt.
+// Returns an error if the config is invalid.
+func NewJWTValidator(config JWTConfig) (ValidatePrincipal, error) {
+   if (len(config.Secret) == 0) {
+      return nil, fmt.Errorf("JWT validator requires a secret");
+   }
+
+   if (config.ClockSkew < 0) {
+      return nil, fmt.Errorf("JWT validator has a negative clock skew (%v)", config.ClockSkew);
+   }
+
+   var algorithms map[string]func() hash.Hash = make(map[string]func() hash.Hash);
+   if (len(config.Algorithms) == 0) {
+      for algorithm, hashFunc := range(jwtHashes) {
+         algorithms[algorithm] = hashFunc;
+      }
+   } else {
+      for _, algorithm := range(config.Algorithms) {
+         hashFunc, ok := jwtHashes[algorithm];
+         if (!ok) {
+            return nil, fmt.Errorf("Unsupported JWT algorithm: %s", algorithm);
+         }
+
+         algorithms[algorithm] = hashFunc;
+      }
+   }
+
+   if (config.UserIdClaim == "") {
+      config.UserIdClaim = "sub";
+   }
+
+   if (config.UserNameClaim == "") {
+      config.UserNameClaim = "name";
+   }
+
+   if (config.Now == nil) {
+      config.Now = time.Now;
+   }
+
+   return func(credential Credential, log Logger) (*Principal, error) {
+      claims, err := verifyJWT(credential.Token, config.Secret, algorithms);
+      if (err != nil) {
+         log.Debug(fmt.Sprintf("Rejected JWT: %v", err));
+         return nil, TokenValidationError{TOKEN_VALIDATION_BAD_SIGNATURE};
+      }
+
+      principal, reason, err := config.buildPrincipal(claims);
+      if (err != nil) {
+         log.Debug(fmt.Sprintf("Rejected JWT: %v", err));
+         return nil, TokenValidationError{reason};
+      }
+
+      principal.Token = credential.Token;
+      return principal, nil;
+   }, nil;
+}
+
+// Check the structure and signature of a token and get its claims.
+func verifyJWT(token string, secret []byte, algorithms map[string]func() hash.Hash) (map[string]interface{}, error) {
+   var parts []string = strings.Split(token, ".");
+   if (len(parts) != 3) {
+      return nil, fmt.Errorf("Expected 3 parts, found %d", len(parts));
+   }
+
+   var header struct {
+      Algorithm string `json:"alg"`
+   };
+
+   err := decodeJWTPart(parts[0], &header);
+   if (err != nil) {
+      return nil, fmt.Errorf("Bad header: %w", err);
+   }
+
+   hashFunc, ok := algorithms[header.Algorithm];
+   if (!ok) {
+      return nil, fmt.Errorf("Algorithm not allowed: '%s'", header.Algorithm);
+   }
+
+   signature, err := base64.RawURLEncoding.DecodeString(parts[2]);
+   if (err != nil) {
+      return nil, fmt.Errorf("Bad signature encoding: %w", err);
+   }
+
+   var mac hash.Hash = hmac.New(hashFunc, secret);
+   mac.Write([]byte(parts[0] + "." + parts[1]));
+   if (!hmac.Equal(signature, mac.Sum(nil))) {
+      return nil, fmt.Errorf("Signature does not match");
+   }
+
+   var claims map[string]interface{} = nil;
+   err = decodeJWTPart(parts[1], &claims);
+   if (err != nil) {
+      return nil, fmt.Errorf("Bad payload: %w", err);
+   }
+
+   if (claims == nil) {
+      return nil, fmt.Errorf("Payload is not an object");
+   }
+
+   return claims, nil;
+}
+
+func decodeJWTPart(part string, target interface{}) error {
+   data, err := base64.RawURLEncoding.DecodeString(part);
+   if (err != nil) {
+      return err;
+   }
+
+   var decoder *json.Decoder = json.NewDecoder(bytes.NewReader(data));
+   decoder.UseNumber();
+   return decoder.Decode(target);
+}
+
+// Check the registered claims and map the rest onto a principal.
+// On failure, also returns the TOKEN_VALIDATION_* reason.
+func (config JWTConfig) buildPrincipal(claims map[string]interface{}) (*Principal, int, error) {
+   var now time.Time = config.Now();
+   var principal Principal = Principal{Claims: claims};
+
+   expires, ok, err := jwtTimeClaim(claims, "exp");
+   if (err != nil) {
+      return nil, TOKEN_VALIDATION_BAD_SIGNATURE, err;
+   }
+
+   if (ok) {
+      // The token is only good before its expiry (RFC 7519 4.1.4).
+      if (!now.Before(expires.Add(config.ClockSkew))) {
+         return nil, TOKEN_VALIDATION_EXPIRED, fmt.Errorf("Expired at %v", expires);
+      }
+
+      principal.ExpiresAt = expires;
+   }
+
+   notBefore, ok, err := jwtTimeClaim(claims, "nbf");
+   if (err != nil) {
+      return nil, TOKEN_VALIDATION_BAD_SIGNATURE, err;
+   }
+
+   if (ok && now.Add(config.ClockSkew).Before(notBefore)) {
+      return nil, TOKEN_VALIDATION_EXPIRED, fmt.Errorf("Not valid until %v", notBefore);
+   }
+
+   if (config.Issuer != "") {
+      issuer, _ := claims["iss"].(string);
+      if (issuer != config.Issuer) {
+         return nil, TOKEN_VALIDATION_BAD_SIGNATURE, fmt.Errorf("Wrong issuer: '%s'", issuer);
+      }
+   }
+
+   if (config.Audience != "" && !jwtHasAudience(claims["aud"], config.Audience)) {
+      return nil, TOKEN_VALIDATION_BAD_SIGNATURE, fmt.Errorf("Wrong audience: %v", claims["aud"]);
+   }
+
+   userId, err := jwtIntClaim(claims, config.UserIdClaim);
+   if (err != nil) {
+      return nil, TOKEN_VALIDATION_BAD_SIGNATURE, err;
+   }
+   principal.UserId = userId;
+
+   principal.UserName, _ = claims[config.UserNameClaim].(string);
+
+   return &principal, 0, nil;
+}
+
+// Get a NumericDate claim (seconds since the epoch, possibly fractional).
+// The second return will be false if the claim does not exist.
+func jwtTimeClaim(claims map[string]interface{}, name string) (time.Time, bool, error) {
+   raw, ok := claims[name];
+   if (!ok) {
+      return time.Time{}, false, nil;
+   }
+
+   number, ok := raw.(json.Number);
+   if (!ok) {
+      return time.Time{}, false, fmt.Errorf("Claim (%s) is not a number", name);
+   }
+
+   seconds, err := number.Float64();
+   if (err != nil || math.IsInf(seconds, 0) || math.IsNaN(seconds)) {
+      return time.Time{}, false, fmt.Errorf("Claim (%s) is not a valid time: %s", name, number.String());
+   }
+
+   var whole float64 = math.Floor(seconds);
+   return time.Unix(int64(whole), int64((seconds - whole) * float64(time.Second))), true, nil;
+}
+
+func jwtIntClaim(claims map[string]interface{}, name string) (int, error) {
+   var text string;
+
+   switch value := claims[name].(type) {
+   case json.Number:
+      text = value.String();
+   case string:
+      text = value;
+   case nil:
+      return 0, fmt.Errorf("Claim (%s) is missing", name);
+   default:
+      return 0, fmt.Errorf("Claim (%s) is not an integer", name);
+   }
+
+   value, err := strconv.Atoi(text);
+   if (err != nil) {
+      return 0, fmt.Errorf("Claim (%s) is not an integer: '%s'", name, text);
+   }
+
+   return value, nil;
+}
+
+// The "aud" claim may either be a single string or a list of strings.
+func jwtHasAudience(claim interface{}, audience string) bool {
+   switch value := claim.(type) {
+   case string:
+      return value == audience;
+   case []interface{}:
+      for _, item := range(value) {
+         if (item == audience) {
+            return true;
+         }
+      }
+   }
+
+   return false;
+}
@@ -0,0 +1,153 @@
+package goapi;
+
+import (
+   "crypto/hmac"
+   "encoding/base64"
+   "fmt"
+   "hash"
+   "testing"
+   "time"
+);
+
+var testJWTSecret []byte = []byte("secret");
+var testJWTNow time.Time = time.Unix(1700000000, 0);
+
+func signTestJWT(algorithm string, secret []byte, payload string) string {
+   var header string = base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"alg":"%s","typ":"JWT"}`, algorithm)));
+   var body string = base64.RawURLEncoding.EncodeToString([]byte(payload));
+
+   var hashFunc func() hash.Hash = jwtHashes[algorithm];
+   if (hashFunc == nil) {
+      return header + "." + body + ".";
+   }
+
+   var mac hash.Hash = hmac.New(hashFunc, secret);
+   mac.Write([]byte(header + "." + body));
+   return header + "." + body + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil));
+}
+
+func TestJWTValidator(t *testing.T) {
+   validator, err := NewJWTValidator(JWTConfig{
+      Secret: testJWTSecret,
+      Algorithms: []string{JWT_ALGORITHM_HS256, JWT_ALGORITHM_HS512},
+      Issuer: "auth.example.com",
+      Audience: "api",
+      ClockSkew: time.Minute,
+      UserNameClaim: "preferred_username",
+      Now: func() time.Time { return testJWTNow; },
+   });
+   if (err != nil) {
+      t.Fatalf("Unexpected error: %v", err);
+   }
+
+   const VALID = -1;
+
+   var claims string = `"iss":"auth.example.com","aud":"api","sub":"7","preferred_username":"alice"`;
+
+   tests := []struct{
+      title string
+      token string
+      reason int
+      userId int
+      userName string
+   } {
+      {"HS256", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{` + claims + `}`), VALID, 7, "alice"},
+      {"HS512", signTestJWT(JWT_ALGORITHM_HS512, testJWTSecret, `{` + claims + `}`), VALID, 7, "alice"},
+      {"Numeric Subject", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{"iss":"auth.example.com","aud":"api","sub":8}`), VALID, 8, ""},
+      {"Audience List", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{"iss":"auth.example.com","aud":["web","api"],"sub":"7"}`), VALID, 7, ""},
+      {"Not Expired", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{` + claims + `,"exp":1700000001}`), VALID, 7, "alice"},
+      {"Expired Within Skew", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{` + claims + `,"exp":1699999990}`), VALID, 7, "alice"},
+      {"Expired Just Within Skew", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{` + claims + `,"exp":1699999941}`), VALID, 7, "alice"},
+      {"Expires At Skew Boundary", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{` + claims + `,"exp":1699999940}`), TOKEN_VALIDATION_EXPIRED, 0, ""},
+      {"Not Before Within Skew", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{` + claims + `,"nbf":1700000030}`), VALID, 7, "alice"},
+
+      {"Expired", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{` + claims + `,"exp":1699999000}`), TOKEN_VALIDATION_EXPIRED, 0, ""},
+      {"Not Yet Valid", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{` + claims + `,"nbf":1700001000}`), TOKEN_VALIDATION_EXPIRED, 0, ""},
+
+      {"Wrong Secret", signTestJWT(JWT_ALGORITHM_HS256, []byte("other"), `{` + claims + `}`), TOKEN_VALIDATION_BAD_SIGNATURE, 0, ""},
+      {"Disallowed Algorithm", signTestJWT(JWT_ALGORITHM_HS384, testJWTSecret, `{` + claims + `}`), TOKEN_VALIDATION_BAD_SIGNATURE, 0, ""},
+      {"None Algorithm", signTestJWT("none", testJWTSecret, `{` + claims + `}`), TOKEN_VALIDATION_BAD_SIGNATURE, 0, ""},
+      {"Wrong Issuer", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{"iss":"evil","aud":"api","sub":"7"}`), TOKEN_VALIDATION_BAD_SIGNATURE, 0, ""},
+      {"Wrong Audience", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{"iss":"auth.example.com","aud":["web"],"sub":"7"}`), TOKEN_VALIDATION_BAD_SIGNATURE, 0, ""},
+      {"Missing Subject", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{"iss":"auth.example.com","aud":"api"}`), TOKEN_VALIDATION_BAD_SIGNATURE, 0, ""},
+      {"Bad Subject", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{"iss":"auth.example.com","aud":"api","sub":"alice"}`), TOKEN_VALIDATION_BAD_SIGNATURE, 0, ""},
+      {"Bad Expiry", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `{` + claims + `,"exp":"tomorrow"}`), TOKEN_VALIDATION_BAD_SIGNATURE, 0, ""},
+      {"Bad Payload", signTestJWT(JWT_ALGORITHM_HS256, testJWTSecret, `[1, 2]`), TOKEN_VALIDATION_BAD_SIGNATURE, 0, ""},
+      {"Garbage", "not.a.jwt", TOKEN_VALIDATION_BAD_SIGNATURE, 0, ""},
+      {"Two Parts", "abc.def", TOKEN_VALIDATION_BAD_SIGNATURE, 0, ""},
+   };
+
+   for _, test := range(tests) {
+      principal, err := validator(Credential{Token: test.token}, ConsoleLogger{});
+
+      if (test.reason == VALID) {
+         if (err != nil) {
+            failTest(t, test.title, "no error", err);
+            continue;
+         }
+
+         if (principal.UserId != test.userId || principal.UserName != test.userName || principal.Token != test.token) {
+            failTest(t, test.title, fmt.Sprintf("%d %s", test.userId, test.userName), fmt.Sprintf("%d %s", principal.UserId, principal.UserName));
+         }
+
+         continue;
+      }
+
+      validationErr, ok := err.(TokenValidationError);
+      if (!ok) {
+         failTest(t, test.title, TokenReasonName(test.reason), err);
+         continue;
+      }
+
+      if (validationErr.Reason != test.reason) {
+         failTest(t, test.title, TokenReasonName(test.reason), TokenReasonName(validationErr.Reason));
+      }
+   }
+}
+
+func TestJWTValidatorClaims(t *testing.T) {
+   validator, err := NewJWTValidator(JWTConfig{
+      Secret: testJWTSecret,
+      UserIdClaim: "uid",
+      Now: func() time.Time { return testJWTNow; },
+   });
+   if (err != nil) {
+      t.Fatalf("Unexpected error: %v", err);
+   }
+
+   var token string = signTestJWT(JWT_ALGORITHM_HS384, testJWTSecret, `{"uid":3,"name":"bob","exp":1700000100,"tenant":"acme"}`);
+   principal, err := validator(Credential{Token: token}, ConsoleLogger{});
+   if (err != nil) {
+      t.Fatalf("Unexpected error: %v", err);
+   }
+
+   if (principal.UserId != 3 || principal.UserName != "bob") {
+      failTest(t, "User", "3 bob", fmt.Sprintf("%d %s", principal.UserId, principal.UserName));
+   }
+
+   if (!principal.ExpiresAt.Equal(time.Unix(1700000100, 0))) {
+      failTest(t, "Expires At", time.Unix(1700000100, 0), principal.ExpiresAt);
+   }
+
+   if (principal.Claims["tenant"] != "acme") {
+      failTest(t, "Claims", "acme", principal.Claims["tenant"]);
+   }
+}
+
+func TestJWTValidatorConfig(t *testing.T) {
+   tests := []struct{
+      title string
+      config JWTConfig
+   } {
+      {"No Secret", JWTConfig{}},
+      {"Unknown Algorithm", JWTConfig{Secret: testJWTSecret, Algorithms: []string{"RS256"}}},
+      {"Negative Skew", JWTConfig{Secret: testJWTSecret, ClockSkew: -time.Second}},
+   };
+
+   for _, test := range(tests) {
+      _, err := NewJWTValidator(test.config);
+      if (err == nil) {
+         failTest(t, test.title, "an error", nil);
+      }
+   }
+}
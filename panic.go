@@ -0,0 +1,52 @@
+package goapi;
+
+import (
+   "fmt"
+   "net/http"
+   "runtime/debug"
+)
+
+// Called whenever a handler panics (after the panic has been logged and before the error response is sent).
+// Use this to send panics to an error reporting service.
+// |stack| is the stack trace of the panicking goroutine.
+type PanicReporter func(recovered interface{}, stack []byte, request *http.Request)
+
+// The error given to the error responder when a handler panics.
+type PanicError struct {
+   // The value passed to panic().
+   Value interface{}
+   Stack []byte
+};
+
+func (err PanicError) Error() string {
+   return fmt.Sprintf("API handler panicked: %v", err.Value);
+}
+
+// Recover from a panic in the handler and respond with a 500.
+// Must be deferred directly.
+// http.ErrAbortHandler is passed along (it is how handlers abort a response on purpose).
+// If the handler already started writing the response, then the error response will not make it out cleanly.
+func (method ApiMethod) recoverPanic(response http.ResponseWriter, request *http.Request) {
+   recovered := recover();
+   if (recovered == nil) {
+      return;
+   }
+
+   if (recovered == http.ErrAbortHandler) {
+      panic(recovered);
+   }
+
+   // Panics from other goroutines (see serveWithTimeout()) already come with their stack.
+   panicErr, ok := recovered.(PanicError);
+   if (!ok) {
+      panicErr = PanicError{recovered, debug.Stack()};
+   }
+
+   method.log.ErrorE(fmt.Sprintf("API handler (%s) panicked:\n%s", method.path, string(panicErr.Stack)), panicErr);
+
+   if (method.panicReporter != nil) {
+      method.panicReporter(panicErr.Value, panicErr.Stack, request);
+   }
+
+   method.sendErrorResponse(panicErr, http.StatusInternalServerError, response, request);
+}
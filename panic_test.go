@@ -0,0 +1,68 @@
+package goapi;
+
+import (
+   "context"
+   "net/http"
+   "net/http/httptest"
+   "strings"
+   "testing"
+);
+
+func TestPanicRecovery(t *testing.T) {
+   var reported interface{} = nil;
+   var reportedStack []byte = nil;
+
+   factory := ApiMethodFactory{};
+   factory.SetPanicReporter(func(recovered interface{}, stack []byte, request *http.Request) {
+      reported = recovered;
+      reportedStack = stack;
+   });
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+   } {
+      {"Basic", factory.NewApiMethod("/panic", func() { panic("boom"); }, false, []ApiMethodParam{})},
+      {"Typed", NewTypedApiMethod(factory, "/panic", false, func(ctx context.Context, request struct{}) (interface{}, error) {
+         panic("boom");
+      })},
+   };
+
+   for _, test := range(tests) {
+      reported = nil;
+      reportedStack = nil;
+
+      request := httptest.NewRequest(http.MethodGet, "/panic", nil);
+      response := httptest.NewRecorder();
+      test.method.Middleware()(response, request);
+
+      if (response.Code != http.StatusInternalServerError) {
+         failTest(t, test.title + " (status)", http.StatusInternalServerError, response.Code);
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != `{"Success":false,"Code":500}`) {
+         failTest(t, test.title, `{"Success":false,"Code":500}`, response.Body.String());
+      }
+
+      if (reported != "boom") {
+         failTest(t, test.title + " (reported)", "boom", reported);
+      }
+
+      if (!strings.Contains(string(reportedStack), "panic")) {
+         failTest(t, test.title + " (stack)", "a stack trace", string(reportedStack));
+      }
+   }
+}
+
+func TestPanicRecoveryAbort(t *testing.T) {
+   factory := ApiMethodFactory{};
+   method := factory.NewApiMethod("/abort", func() { panic(http.ErrAbortHandler); }, false, []ApiMethodParam{});
+
+   defer func() {
+      if (recover() != http.ErrAbortHandler) {
+         t.Errorf("ErrAbortHandler was not passed along");
+      }
+   }();
+
+   method.Middleware()(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil));
+}
@@ -0,0 +1,137 @@
+package goapi;
+
+import (
+   "fmt"
+   "math"
+   "reflect"
+   "strconv"
+   "strings"
+   "time"
+)
+
+// Information about each API_PARAM_TYPE_*.
+type paramTypeInfo struct {
+   // The name used when describing a param.
+   name string
+   // The exact type the handler must use for the param.
+   // nil for types that the handler gets to choose (JSON).
+   goType reflect.Type
+   // Parse a (non-empty) string value into a value of |goType|.
+   // nil for types that are not parsed from strings (files and JSON).
+   parse func(value string) (interface{}, error)
+}
+
+var paramTypes map[int]paramTypeInfo = map[int]paramTypeInfo{
+   API_PARAM_TYPE_INT: paramTypeInfo{"int", reflect.TypeOf(int(0)), parseInt},
+   API_PARAM_TYPE_STRING: paramTypeInfo{"string", reflect.TypeOf(""), parseString},
+   API_PARAM_TYPE_FILE: paramTypeInfo{"File", reflect.TypeOf(File{}), nil},
+   API_PARAM_TYPE_JSON: paramTypeInfo{"JSON", nil, nil},
+   API_PARAM_TYPE_BOOL: paramTypeInfo{"bool", reflect.TypeOf(false), parseBool},
+   API_PARAM_TYPE_FLOAT: paramTypeInfo{"float64", reflect.TypeOf(float64(0)), parseFloat},
+   API_PARAM_TYPE_INT64: paramTypeInfo{"int64", reflect.TypeOf(int64(0)), parseInt64},
+   API_PARAM_TYPE_UINT: paramTypeInfo{"uint", reflect.TypeOf(uint(0)), parseUint},
+   API_PARAM_TYPE_TIME: paramTypeInfo{"time.Time", reflect.TypeOf(time.Time{}), parseTime},
+   API_PARAM_TYPE_DURATION: paramTypeInfo{"time.Duration", reflect.TypeOf(time.Duration(0)), parseDuration},
+};
+
+// Is this a type that is parsed from a single string value.
+func isScalarParamType(paramType int) bool {
+   info, ok := paramTypes[paramType];
+   return ok && info.parse != nil;
+}
+
+// Get the API_PARAM_TYPE_* that uses |goType|.
+func paramTypeForGoType(goType reflect.Type) (int, bool) {
+   for paramType, info := range(paramTypes) {
+      if (info.goType != nil && info.goType == goType) {
+         return paramType, true;
+      }
+   }
+
+   return 0, false;
+}
+
+// Format a (parsed) value the same way it would be sent in a request.
+// Lists are formatted as "[a, b, ...]".
+func formatParamValue(value interface{}) string {
+   timeValue, ok := value.(time.Time);
+   if (ok) {
+      return timeValue.Format(time.RFC3339);
+   }
+
+   if (value != nil && reflect.TypeOf(value).Kind() == reflect.Slice) {
+      var parts []string = make([]string, 0);
+      for _, element := range(listValues(value)) {
+         parts = append(parts, formatParamValue(element));
+      }
+
+      return "[" + strings.Join(parts, ", ") + "]";
+   }
+
+   return fmt.Sprintf("%v", value);
+}
+
+// Get the elements of a (slice) list value.
+func listValues(list interface{}) []interface{} {
+   var listValue reflect.Value = reflect.ValueOf(list);
+
+   var values []interface{} = make([]interface{}, 0, listValue.Len());
+   for i := 0; i < listValue.Len(); i++ {
+      values = append(values, listValue.Index(i).Interface());
+   }
+
+   return values;
+}
+
+func parseInt(value string) (interface{}, error) {
+   return strconv.Atoi(value);
+}
+
+func parseString(value string) (interface{}, error) {
+   return value, nil;
+}
+
+// Only "true" and "false" (in any case) are accepted.
+func parseBool(value string) (interface{}, error) {
+   switch strings.ToLower(value) {
+   case "true":
+      return true, nil;
+   case "false":
+      return false, nil;
+   default:
+      return false, fmt.Errorf("Expected 'true' or 'false', found: '%s'", value);
+   }
+}
+
+// NaN and infinities are not accepted.
+func parseFloat(value string) (interface{}, error) {
+   floatValue, err := strconv.ParseFloat(value, 64);
+   if (err != nil) {
+      return float64(0), err;
+   }
+
+   if (math.IsNaN(floatValue) || math.IsInf(floatValue, 0)) {
+      return float64(0), fmt.Errorf("Expected a finite number, found: '%s'", value);
+   }
+
+   return floatValue, nil;
+}
+
+func parseInt64(value string) (interface{}, error) {
+   return strconv.ParseInt(value, 10, 64);
+}
+
+func parseUint(value string) (interface{}, error) {
+   uintValue, err := strconv.ParseUint(value, 10, strconv.IntSize);
+   return uint(uintValue), err;
+}
+
+// RFC 3339 timestamps (eg "2006-01-02T15:04:05Z07:00").
+func parseTime(value string) (interface{}, error) {
+   return time.Parse(time.RFC3339, value);
+}
+
+// Go duration strings (eg "1h30m").
+func parseDuration(value string) (interface{}, error) {
+   return time.ParseDuration(value);
+}
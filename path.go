@@ -0,0 +1,161 @@
+package goapi;
+
+import (
+   "fmt"
+   "strings"
+)
+
+// A parsed ApiMethod path, which may include placeholders for path parameters.
+// Placeholders take up an entire segment of the path and look like: "/users/{id}/files/{fileId}".
+// Placeholder names must be valid Go identifiers (this matches the patterns used by http.ServeMux).
+type pathTemplate struct {
+   // Literal segments will have an empty name.
+   // Placeholder segments will have an empty literal.
+   literals []string
+   names []string
+}
+
+func parsePathTemplate(path string) (pathTemplate, error) {
+   var template pathTemplate = pathTemplate{make([]string, 0), make([]string, 0)};
+   var seenNames map[string]bool = make(map[string]bool);
+
+   for _, segment := range(splitPath(path)) {
+      if (!strings.ContainsAny(segment, "{}")) {
+         template.literals = append(template.literals, segment);
+         template.names = append(template.names, "");
+         continue;
+      }
+
+      if (!strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}")) {
+         return pathTemplate{}, fmt.Errorf("Path placeholders must take up an entire segment, found: '%s'", segment);
+      }
+
+      var name string = segment[1:len(segment) - 1];
+      if (!isIdentifier(name)) {
+         return pathTemplate{}, fmt.Errorf("Path placeholder names must be identifiers, found: '%s'", segment);
+      }
+
+      if (seenNames[name]) {
+         return pathTemplate{}, fmt.Errorf("Duplicate path placeholder: '%s'", segment);
+      }
+      seenNames[name] = true;
+
+      template.literals = append(template.literals, "");
+      template.names = append(template.names, name);
+   }
+
+   return template, nil;
+}
+
+// The names of all the placeholders (in order).
+func (template pathTemplate) placeholders() []string {
+   var rtn []string = make([]string, 0);
+
+   for _, name := range(template.names) {
+      if (name != "") {
+         rtn = append(rtn, name);
+      }
+   }
+
+   return rtn;
+}
+
+func (template pathTemplate) hasPlaceholders() bool {
+   return len(template.placeholders()) > 0;
+}
+
+// A normalized form of the template where all placeholders look the same.
+// Two templates with the same key will match the exact same paths.
+func (template pathTemplate) key() string {
+   var segments []string = make([]string, len(template.literals));
+
+   for i, literal := range(template.literals) {
+      if (template.names[i] != "") {
+         segments[i] = "{}";
+      } else {
+         segments[i] = literal;
+      }
+   }
+
+   return joinPaths("", strings.Join(segments, "/"));
+}
+
+// The number of literal segments, used to prefer more specific templates.
+func (template pathTemplate) numLiterals() int {
+   return len(template.literals) - len(template.placeholders());
+}
+
+// Could any path match both this template and |other|.
+func (template pathTemplate) overlaps(other pathTemplate) bool {
+   if (len(template.literals) != len(other.literals)) {
+      return false;
+   }
+
+   for i, literal := range(template.literals) {
+      if (template.names[i] != "" || other.names[i] != "") {
+         continue;
+      }
+
+      if (literal != other.literals[i]) {
+         return false;
+      }
+   }
+
+   return true;
+}
+
+// Match a request path against this template.
+// On a match, the values for all the placeholders are returned.
+func (template pathTemplate) match(path string) (map[string]string, bool) {
+   var segments []string = splitPath(path);
+   if (len(segments) != len(template.literals)) {
+      return nil, false;
+   }
+
+   var values map[string]string = make(map[string]string);
+
+   for i, segment := range(segments) {
+      if (template.names[i] == "") {
+         if (segment != template.literals[i]) {
+            return nil, false;
+         }
+      } else {
+         if (segment == "") {
+            return nil, false;
+         }
+
+         values[template.names[i]] = segment;
+      }
+   }
+
+   return values, true;
+}
+
+func splitPath(path string) []string {
+   path = strings.Trim(path, "/");
+   if (path == "") {
+      return []string{};
+   }
+
+   return strings.Split(path, "/");
+}
+
+func isIdentifier(name string) bool {
+   if (name == "") {
+      return false;
+   }
+
+   for i, char := range(name) {
+      if (char == '_' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z')) {
+         continue;
+      }
+
+      if (i > 0 && char >= '0' && char <= '9') {
+         continue;
+      }
+
+      return false;
+   }
+
+   return true;
+}
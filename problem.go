@@ -0,0 +1,92 @@
+package goapi;
+
+import (
+   "errors"
+   "net/http"
+)
+
+const PROBLEM_CONTENT_TYPE = "application/problem+json"
+
+// An RFC 7807 problem details object.
+// Use ProblemErrorResponder as the factory's error responder to send all errors as problems.
+// Problems are always sent as application/problem+json (no matter what the factory's content type is).
+type Problem struct {
+   // A URI that identifies the kind of problem ("about:blank" means that Title is just the status text).
+   Type string `json:"type"`
+   Title string `json:"title"`
+   Status int `json:"status"`
+   // A (client-safe) explanation of this occurrence of the problem.
+   Detail string `json:"detail,omitempty"`
+   // Identifies this occurrence of the problem, the request's path is used if empty.
+   Instance string `json:"instance,omitempty"`
+
+   // Extension members.
+
+   // The code of an APIError.
+   Code string `json:"code,omitempty"`
+   // The reason a token failed (only for TokenValidationErrors), see TokenReasonName().
+   TokenReason string `json:"token-reason,omitempty"`
+   // The params that failed (only for ParamErrors).
+   InvalidParams []ProblemParam `json:"invalid-params,omitempty"`
+}
+
+type ProblemParam struct {
+   Name string `json:"name"`
+   // A machine-readable reason, see ParamError.Code().
+   Reason string `json:"reason"`
+   // The violated rule (see PARAM_RULE_*), only for constraints.
+   Rule string `json:"rule,omitempty"`
+   // A client-safe description of the failure.
+   Message string `json:"message,omitempty"`
+}
+
+func (problem Problem) ContentType() string {
+   return PROBLEM_CONTENT_TYPE;
+}
+
+// An ErrorResponder that responds with Problems.
+// Only the descriptions of known errors (APIError, TokenValidationError, AuthorizationError, and ParamErrors) are exposed.
+// The roles and scopes missing from an AuthorizationError are not exposed.
+func ProblemErrorResponder(err error, httpStatus int) interface{} {
+   var problem Problem = Problem{
+      Type: "about:blank",
+      Title: http.StatusText(httpStatus),
+      Status: httpStatus,
+   };
+
+   var tokenErr TokenValidationError;
+   var authErr AuthorizationError;
+   var paramErrs ParamErrors;
+   var paramErr ParamError;
+
+   apiErr, isAPIError := asAPIError(err);
+
+   if (isAPIError) {
+      problem.Detail = apiErr.Message;
+      problem.Code = apiErr.Code;
+   } else if (errors.As(err, &tokenErr)) {
+      problem.Detail = tokenErr.Description();
+      problem.TokenReason = TokenReasonName(tokenErr.Reason);
+   } else if (errors.As(err, &authErr)) {
+      problem.Detail = authErr.Description();
+   } else if (errors.As(err, &paramErrs)) {
+      problem.Detail = "One or more params are invalid";
+      for _, paramErr := range(paramErrs) {
+         problem.InvalidParams = append(problem.InvalidParams, toProblemParam(paramErr));
+      }
+   } else if (errors.As(err, &paramErr)) {
+      problem.Detail = "One or more params are invalid";
+      problem.InvalidParams = []ProblemParam{toProblemParam(paramErr)};
+   }
+
+   return problem;
+}
+
+func toProblemParam(paramErr ParamError) ProblemParam {
+   return ProblemParam{
+      Name: paramErr.Param,
+      Reason: paramErr.Code(),
+      Rule: paramErr.Rule,
+      Message: paramErr.Message,
+   };
+}
@@ -0,0 +1,120 @@
+package goapi;
+
+import (
+   "fmt"
+   "net/http"
+   "net/http/httptest"
+   "strings"
+   "testing"
+);
+
+func TestProblemErrorResponder(t *testing.T) {
+   factory := ApiMethodFactory{};
+   factory.SetGeneralErrorResponser(ProblemErrorResponder);
+   factory.SetTokenValidator(func(token string, log Logger) (int, string, error) {
+      if (token != "good") {
+         return 0, "", TokenValidationError{TOKEN_VALIDATION_EXPIRED};
+      }
+
+      return 1, "alice", nil;
+   });
+
+   router := factory.NewRouter("/api",
+      factory.NewApiMethod("/items", handler_echoInt, false, []ApiMethodParam{
+         ApiMethodParam{Name: "val", ParamType: API_PARAM_TYPE_INT, Required: true, Min: 1},
+      }).SetAllowedMethods(http.MethodGet),
+      factory.NewApiMethod("/secret", handler_empty, true, []ApiMethodParam{}),
+      factory.NewApiMethod("/admin", handler_empty, true, []ApiMethodParam{}).SetRequiredRoles("admin"),
+      factory.NewApiMethod("/fail", func() error { return fmt.Errorf("Internal details"); }, false, []ApiMethodParam{}),
+      factory.NewApiMethod("/conflict", func() error {
+         return APIError{Status: http.StatusConflict, Code: "name_taken", Message: "That name is taken", Cause: fmt.Errorf("Internal details")};
+      }, false, []ApiMethodParam{}),
+   );
+
+   tests := []struct{
+      title string
+      httpMethod string
+      path string
+      token string
+      status int
+      contentType string
+      response string
+   } {
+      {
+         "Success", http.MethodGet, "/api/items?val=1", "",
+         http.StatusOK, "application/json; charset=UTF-8", `1`,
+      },
+      {
+         "Missing Param", http.MethodGet, "/api/items", "",
+         http.StatusBadRequest, PROBLEM_CONTENT_TYPE,
+         `{"type":"about:blank","title":"Bad Request","status":400,"detail":"One or more params are invalid","instance":"/api/items","invalid-params":[{"name":"val","reason":"missing","message":"is required"}]}`,
+      },
+      {
+         "Bad Type", http.MethodGet, "/api/items?val=a", "",
+         http.StatusBadRequest, PROBLEM_CONTENT_TYPE,
+         `{"type":"about:blank","title":"Bad Request","status":400,"detail":"One or more params are invalid","instance":"/api/items","invalid-params":[{"name":"val","reason":"bad-type","message":"must be a int"}]}`,
+      },
+      {
+         "Constraint", http.MethodGet, "/api/items?val=0", "",
+         http.StatusBadRequest, PROBLEM_CONTENT_TYPE,
+         `{"type":"about:blank","title":"Bad Request","status":400,"detail":"One or more params are invalid","instance":"/api/items","invalid-params":[{"name":"val","reason":"min","rule":"min","message":"must be at least 1"}]}`,
+      },
+      {
+         "Bad Token", http.MethodGet, "/api/secret", "bad",
+         http.StatusUnauthorized, PROBLEM_CONTENT_TYPE,
+         `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"Token is expired","instance":"/api/secret","token-reason":"expired"}`,
+      },
+      {
+         "No Token", http.MethodGet, "/api/secret", "",
+         http.StatusUnauthorized, PROBLEM_CONTENT_TYPE,
+         `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"Token does not exist","instance":"/api/secret","token-reason":"no-token"}`,
+      },
+      {
+         "Forbidden", http.MethodGet, "/api/admin", "good",
+         http.StatusForbidden, PROBLEM_CONTENT_TYPE,
+         `{"type":"about:blank","title":"Forbidden","status":403,"detail":"Insufficient permissions","instance":"/api/admin"}`,
+      },
+      {
+         "Handler Error", http.MethodGet, "/api/fail", "",
+         http.StatusInternalServerError, PROBLEM_CONTENT_TYPE,
+         `{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"/api/fail"}`,
+      },
+      {
+         "API Error", http.MethodGet, "/api/conflict", "",
+         http.StatusConflict, PROBLEM_CONTENT_TYPE,
+         `{"type":"about:blank","title":"Conflict","status":409,"detail":"That name is taken","instance":"/api/conflict","code":"name_taken"}`,
+      },
+      {
+         "Not Allowed", http.MethodPost, "/api/items", "",
+         http.StatusMethodNotAllowed, PROBLEM_CONTENT_TYPE,
+         `{"type":"about:blank","title":"Method Not Allowed","status":405,"instance":"/api/items"}`,
+      },
+      {
+         "Not Found", http.MethodGet, "/api/nothing", "",
+         http.StatusNotFound, PROBLEM_CONTENT_TYPE,
+         `{"type":"about:blank","title":"Not Found","status":404,"instance":"/api/nothing"}`,
+      },
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(test.httpMethod, test.path, nil);
+      if (test.token != "") {
+         request.Header.Set("Authorization", "Bearer " + test.token);
+      }
+
+      response := httptest.NewRecorder();
+      router.ServeHTTP(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title + " (status)", test.status, response.Code);
+      }
+
+      if (response.Header().Get("Content-Type") != test.contentType) {
+         failTest(t, test.title + " (content type)", test.contentType, response.Header().Get("Content-Type"));
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
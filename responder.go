@@ -1,19 +1,93 @@
 package goapi;
 
+import (
+   "errors"
+   "net/http"
+)
+
 // Error responders decide what kind of response to give when some error arise.
 // The returned object will later be serialized (see ApiMethodfactory.errorResponder).
 // By default, a simple struct response is given.
 // If you use the default error responder with a custom serialiszer, it should be able to handle GeneralStatus.
 // These responses are sent back to the user, so make sure to only send safe data.
+// If the returned object implements ContentTyper, then its content type is used instead of the factory's.
 
 // |err| may be nil.
 type ErrorResponder func(err error, httpStatus int) interface{}
 
+// Response objects that know their own content type (eg Problem).
+type ContentTyper interface {
+   ContentType() string
+}
+
 type GeneralStatus struct {
    Success bool
    Code int
+   // The code and message of an APIError.
+   ErrorCode string `json:",omitempty"`
+   Message string `json:",omitempty"`
+   // The params that failed (only for ParamErrors).
+   Params []GeneralParam `json:",omitempty"`
+}
+
+// A param that failed, safe to send to the client (the offending value is left out).
+type GeneralParam struct {
+   Name string
+   // See ParamError.Code().
+   Code string
+   Message string
 }
 
 func GeneralErrorResponder(err error, httpStatus int) interface{} {
-   return GeneralStatus{false, httpStatus};
+   var status GeneralStatus = GeneralStatus{Success: false, Code: httpStatus};
+
+   var paramErrs ParamErrors;
+   var paramErr ParamError;
+
+   apiErr, ok := asAPIError(err);
+   if (ok) {
+      status.ErrorCode = apiErr.Code;
+      status.Message = apiErr.Message;
+   } else if (errors.As(err, &paramErrs)) {
+      for _, paramErr := range(paramErrs) {
+         status.Params = append(status.Params, toGeneralParam(paramErr));
+      }
+   } else if (errors.As(err, &paramErr)) {
+      status.Params = []GeneralParam{toGeneralParam(paramErr)};
+   }
+
+   return status;
+}
+
+func toGeneralParam(paramErr ParamError) GeneralParam {
+   return GeneralParam{
+      Name: paramErr.Param,
+      Code: paramErr.Code(),
+      Message: paramErr.Message,
+   };
+}
+
+// Invoke |responder| for a request.
+// Problems without an instance will get the request's path as their instance.
+func buildErrorResponse(responder ErrorResponder, err error, httpStatus int, request *http.Request) interface{} {
+   var responseObj interface{} = responder(err, httpStatus);
+
+   problem, ok := responseObj.(Problem);
+   if (ok && problem.Instance == "" && request != nil && request.URL != nil) {
+      problem.Instance = request.URL.Path;
+      responseObj = problem;
+   }
+
+   return responseObj;
+}
+
+// Get the content type to send an error response as.
+// Error responses that are ContentTypers pick their own, otherwise |contentType| is used.
+func errorContentType(responseObj interface{}, contentType string) string {
+   contentTyper, ok := responseObj.(ContentTyper);
+   if (ok && contentTyper.ContentType() != "") {
+      return contentTyper.ContentType();
+   }
+
+   return contentType;
 }
@@ -0,0 +1,242 @@
+package goapi;
+
+import (
+   "fmt"
+   "net/http"
+   "sort"
+   "strings"
+)
+
+// A Router owns a set of ApiMethods and dispatches requests to them.
+// Routers implement http.Handler, so they can be passed directly to http.Handle() or http.ListenAndServe().
+// Requests for unknown paths get a 404 built with the factory's error responder.
+type Router struct {
+   prefix string
+   table *routeTable
+   log Logger
+   serializer Serializer
+   contentType string
+   errorResponder ErrorResponder
+}
+
+// The routes are shared between a router and all of its groups.
+// Routes are keyed by their path template's key (see pathTemplate.key()).
+type routeTable struct {
+   routes map[string][]route
+   // All the templates that have placeholders, most specific first.
+   templates []pathTemplate
+}
+
+type route struct {
+   template pathTemplate
+   // The live method, so changes made after it was added (eg SetRequiredRoles()) still apply.
+   method *ApiMethod
+}
+
+// Create a new router where all methods will be mounted under |prefix| (which may be empty).
+func (factory ApiMethodFactory) NewRouter(prefix string, methods ...*ApiMethod) *Router {
+   (&factory).setDefaults();
+
+   var router *Router = &Router{
+      prefix: joinPaths("", prefix),
+      table: &routeTable{make(map[string][]route), make([]pathTemplate, 0)},
+      log: factory.log,
+      serializer: factory.serializer,
+      contentType: factory.contentType,
+      errorResponder: factory.errorResponder,
+   };
+
+   return router.Add(methods...);
+}
+
+// Get a router that adds its own prefix on top of this router's prefix.
+// The group shares all of its routes with this router (and any other groups).
+func (router *Router) Group(prefix string, methods ...*ApiMethod) *Router {
+   var group Router = *router;
+   group.prefix = joinPaths(router.prefix, prefix);
+
+   return group.Add(methods...);
+}
+
+func (router Router) Prefix() string {
+   return router.prefix;
+}
+
+// Register methods with this router.
+// The methods should be fully configured before they are added.
+// Will panic if a method conflicts with one already registered.
+// Two methods conflict if they have the same path and they both allow at least one of the same HTTP methods.
+// Paths that only differ in the names of their placeholders are considered the same.
+// Paths with placeholders that are equally specific (have the same number of literal segments) and could match the same request
+// (eg "/a/{x}" and "/{y}/b") are ambiguous, so they also conflict if they share an HTTP method.
+// Returns this so you can chain.
+func (router *Router) Add(methods ...*ApiMethod) *Router {
+   for _, method := range(methods) {
+      if (method == nil) {
+         router.log.Panic(fmt.Sprintf("Nil API method added to router (%s)", router.prefix));
+      }
+
+      var path string = joinPaths(router.prefix, method.Path());
+
+      template, err := parsePathTemplate(path);
+      if (err != nil) {
+         router.log.Panic(fmt.Sprintf("Bad API method path (%s): %v", path, err));
+      }
+
+      if (len(template.placeholders()) != len(method.template.placeholders())) {
+         router.log.Panic(fmt.Sprintf("Router prefix (%s) cannot have path placeholders", router.prefix));
+      }
+
+      var key string = template.key();
+      for _, existing := range(router.table.routes[key]) {
+         if (methodsOverlap(*existing.method, *method)) {
+            router.log.Panic(fmt.Sprintf("Duplicate API method path (%s). Methods that share a path must allow disjoint HTTP methods.", path));
+         }
+      }
+
+      for _, other := range(router.table.templates) {
+         if (other.key() == key || other.numLiterals() != template.numLiterals() || !other.overlaps(template)) {
+            continue;
+         }
+
+         for _, existing := range(router.table.routes[other.key()]) {
+            if (methodsOverlap(*existing.method, *method)) {
+               router.log.Panic(fmt.Sprintf("Ambiguous API method paths (%s and %s). Equally specific paths that can match the same request must allow disjoint HTTP methods.", path, other.key()));
+            }
+         }
+      }
+
+      if (template.hasPlaceholders() && len(router.table.routes[key]) == 0) {
+         router.table.templates = append(router.table.templates, template);
+         sort.SliceStable(router.table.templates, func(i int, j int) bool {
+            return router.table.templates[i].numLiterals() > router.table.templates[j].numLiterals();
+         });
+      }
+
+      router.table.routes[key] = append(router.table.routes[key], route{template, method});
+   }
+
+   return router;
+}
+
+// Find all the routes that match |path|, most specific first.
+// Exact (placeholder-free) paths come before paths with placeholders.
+func (table routeTable) lookup(path string) []route {
+   var rtn []route = make([]route, 0);
+   rtn = append(rtn, table.routes[joinPaths("", path)]...);
+
+   for _, template := range(table.templates) {
+      _, ok := template.match(path);
+      if (ok) {
+         rtn = append(rtn, table.routes[template.key()]...);
+      }
+   }
+
+   return rtn;
+}
+
+// Make the path values visible to the method (through http.Request.PathValue()) and invoke it.
+func (route route) serve(response http.ResponseWriter, request *http.Request) {
+   values, _ := route.template.match(request.URL.Path);
+   for name, value := range(values) {
+      request.SetPathValue(name, value);
+   }
+
+   route.method.Middleware()(response, request);
+}
+
+func (router *Router) ServeHTTP(response http.ResponseWriter, request *http.Request) {
+   var routes []route = router.table.lookup(request.URL.Path);
+
+   if (len(routes) == 0) {
+      router.log.Debug(fmt.Sprintf("No API method for path: %s", request.URL.Path));
+      router.sendErrorResponse(http.StatusNotFound, response, request);
+      return;
+   }
+
+   // A single route can take care of its own OPTIONS and 405 responses.
+   if (len(routes) == 1) {
+      routes[0].serve(response, request);
+      return;
+   }
+
+   // The most specific route that allows the method wins.
+   if (request.Method != http.MethodOptions) {
+      for _, route := range(routes) {
+         if (route.method.allowsMethod(request.Method)) {
+            route.serve(response, request);
+            return;
+         }
+      }
+   }
+
+   // Either a preflight or no method matched, the routes will need to be combined.
+   var allowHeader string = combinedAllowHeader(routes);
+   routes[0].method.setStandardHeaders(response);
+   response.Header().Set("Access-Control-Allow-Methods", allowHeader);
+   response.Header().Set("Allow", allowHeader);
+
+   if (request.Method == http.MethodOptions) {
+      return;
+   }
+
+   router.sendErrorResponse(http.StatusMethodNotAllowed, response, request);
+}
+
+func (router Router) sendErrorResponse(httpStatus int, response http.ResponseWriter, request *http.Request) {
+   var responseObj interface{} = buildErrorResponse(router.errorResponder, nil, httpStatus, request);
+
+   // Any serialization errors will be ignored at this point.
+   responseString, _ := router.serializer(responseObj);
+
+   response.Header().Set("Content-Type", errorContentType(responseObj, router.contentType));
+   response.WriteHeader(httpStatus);
+   fmt.Fprintln(response, responseString);
+}
+
+// Do two methods accept any of the same HTTP methods.
+func methodsOverlap(a ApiMethod, b ApiMethod) bool {
+   for httpMethod := range(knownHTTPMethods) {
+      if (httpMethod != http.MethodOptions && a.allowsMethod(httpMethod) && b.allowsMethod(httpMethod)) {
+         return true;
+      }
+   }
+
+   return false;
+}
+
+// Only used when more than one route matches a path.
+func combinedAllowHeader(routes []route) string {
+   var methods []string = make([]string, 0);
+   var seen map[string]bool = make(map[string]bool);
+
+   for _, route := range(routes) {
+      for _, httpMethod := range(strings.Split(route.method.allowHeader(), ", ")) {
+         if (httpMethod == http.MethodOptions || seen[httpMethod]) {
+            continue;
+         }
+
+         seen[httpMethod] = true;
+         methods = append(methods, httpMethod);
+      }
+   }
+   methods = append(methods, http.MethodOptions);
+
+   return strings.Join(methods, ", ");
+}
+
+// Join two paths with exactly one slash between them.
+// The result will always start with a slash and never end with one (unless it is the root).
+func joinPaths(prefix string, path string) string {
+   var rtn string = strings.Trim(prefix, "/");
+
+   path = strings.Trim(path, "/");
+   if (path != "") {
+      if (rtn != "") {
+         rtn += "/";
+      }
+      rtn += path;
+   }
+
+   return "/" + rtn;
+}
@@ -0,0 +1,264 @@
+package goapi;
+
+import (
+   "fmt"
+   "net/http"
+   "net/http/httptest"
+   "strings"
+   "testing"
+);
+
+func TestRouter(t *testing.T) {
+   factory := ApiMethodFactory{};
+
+   router := factory.NewRouter("/api",
+      factory.NewApiMethod("users/list", handler_return1, false, []ApiMethodParam{}),
+      factory.NewApiMethod("/users", handler_return1, false, []ApiMethodParam{}).SetAllowedMethods(http.MethodGet),
+      factory.NewApiMethod("/users", handler_return2, false, []ApiMethodParam{}).SetAllowedMethods(http.MethodPost),
+   );
+   router.Group("v2").Add(factory.NewApiMethod("/users/list", handler_empty, false, []ApiMethodParam{}));
+
+   tests := []struct{
+      title string
+      httpMethod string
+      path string
+      status int
+      allow string
+   } {
+      {"Basic", http.MethodGet, "/api/users/list", http.StatusOK, ""},
+      {"Trailing Slash", http.MethodGet, "/api/users/list/", http.StatusOK, ""},
+      {"Group", http.MethodGet, "/api/v2/users/list", http.StatusOK, ""},
+      {"Shared Path Get", http.MethodGet, "/api/users", http.StatusOK, ""},
+      {"Shared Path Post", http.MethodPost, "/api/users", http.StatusOK, ""},
+      {"Shared Path Not Allowed", http.MethodDelete, "/api/users", http.StatusMethodNotAllowed, "GET, HEAD, POST, OPTIONS"},
+      {"Shared Path Options", http.MethodOptions, "/api/users", http.StatusOK, "GET, HEAD, POST, OPTIONS"},
+      {"No Prefix", http.MethodGet, "/users/list", http.StatusNotFound, ""},
+      {"Unknown", http.MethodGet, "/api/nothing", http.StatusNotFound, ""},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(test.httpMethod, test.path, nil);
+      response := httptest.NewRecorder();
+      router.ServeHTTP(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title, test.status, response.Code);
+         continue;
+      }
+
+      if (test.allow != "" && response.Header().Get("Allow") != test.allow) {
+         failTest(t, test.title + " Allow", test.allow, response.Header().Get("Allow"));
+      }
+
+      if (test.status == http.StatusNotFound && strings.TrimSpace(response.Body.String()) != `{"Success":false,"Code":404}`) {
+         failTest(t, test.title + " Body", `{"Success":false,"Code":404}`, response.Body.String());
+      }
+   }
+}
+
+// Methods can still be changed after they are added.
+func TestRouterLiveMethods(t *testing.T) {
+   factory := ApiMethodFactory{};
+   factory.SetPrincipalValidator(fakeValidatePrincipal);
+
+   var method *ApiMethod = factory.NewApiMethod("/admin", handler_return1, true, []ApiMethodParam{});
+   router := factory.NewRouter("/api", method);
+   method.SetRequiredRoles("admin");
+
+   tests := []struct{
+      title string
+      token string
+      status int
+   } {
+      {"Admin", "admin", http.StatusOK},
+      {"Missing Role", "user", http.StatusForbidden},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodGet, "/api/admin", nil);
+      request.Header.Set("Authorization", "Bearer " + test.token);
+
+      response := httptest.NewRecorder();
+      router.ServeHTTP(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title, test.status, response.Code);
+      }
+   }
+}
+
+func TestRouterDuplicates(t *testing.T) {
+   tests := []struct{
+      title string
+      first []string
+      second []string
+      valid bool
+   } {
+      {"All Methods", []string{}, []string{}, false},
+      {"All and Get", []string{}, []string{http.MethodGet}, false},
+      {"Same Method", []string{http.MethodGet}, []string{http.MethodPost, http.MethodGet}, false},
+      {"Implicit Head", []string{http.MethodGet}, []string{http.MethodHead}, false},
+      {"Disjoint", []string{http.MethodGet}, []string{http.MethodPost, http.MethodDelete}, true},
+   };
+
+   factory := ApiMethodFactory{};
+   for _, test := range(tests) {
+      func() {
+         defer func() {
+            r := recover();
+            if ((r == nil) != test.valid) {
+               failTest(t, test.title, test.valid, r == nil);
+            }
+         }();
+
+         router := factory.NewRouter("",
+            factory.NewApiMethod("/path", handler_empty, false, []ApiMethodParam{}).SetAllowedMethods(test.first...));
+         router.Group("/").Add(
+            factory.NewApiMethod("path/", handler_empty, false, []ApiMethodParam{}).SetAllowedMethods(test.second...));
+      }();
+   }
+}
+
+func TestRouterPathParams(t *testing.T) {
+   factory := ApiMethodFactory{};
+
+   router := factory.NewRouter("/api",
+      factory.NewApiMethod("/users/{id}/files/{name}", handler_echoIntString, false, []ApiMethodParam{
+         ApiMethodParam{Name: "id", ParamType: API_PARAM_TYPE_INT, Required: true, Source: API_PARAM_SOURCE_PATH},
+         ApiMethodParam{Name: "name", ParamType: API_PARAM_TYPE_STRING, Required: true, Source: API_PARAM_SOURCE_PATH},
+      }),
+      factory.NewApiMethod("/users/{id}/files/latest", handler_int, false, []ApiMethodParam{
+         ApiMethodParam{Name: "id", ParamType: API_PARAM_TYPE_INT, Required: true, Source: API_PARAM_SOURCE_PATH},
+      }),
+   );
+
+   tests := []struct{
+      title string
+      path string
+      status int
+      body string
+   } {
+      {"Basic", "/api/users/5/files/a.txt", http.StatusOK, `"5 a.txt"`},
+      {"More Specific", "/api/users/5/files/latest", http.StatusOK, `null`},
+      {"Bad Int", "/api/users/five/files/a.txt", http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"id","Code":"bad-type","Message":"must be a int"}]}`},
+      {"Missing Segment", "/api/users/5/files", http.StatusNotFound, `{"Success":false,"Code":404}`},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodGet, test.path, nil);
+      response := httptest.NewRecorder();
+      router.ServeHTTP(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title, test.status, response.Code);
+         continue;
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.body) {
+         failTest(t, test.title, test.body, response.Body.String());
+      }
+   }
+}
+
+func handler_echoIntString(someInt int, someString string) interface{} {
+   return fmt.Sprintf("%d %s", someInt, someString);
+}
+
+func TestRouterOverlappingPaths(t *testing.T) {
+   factory := ApiMethodFactory{};
+
+   router := factory.NewRouter("/api",
+      factory.NewApiMethod("/users/me", func() interface{} { return "me"; }, false, []ApiMethodParam{}).SetAllowedMethods(http.MethodGet),
+      newPathEchoMethod(factory, "/users/{id}", "id").SetAllowedMethods(http.MethodDelete),
+      newPathEchoMethod(factory, "/a/{x}", "x").SetAllowedMethods(http.MethodGet),
+      newPathEchoMethod(factory, "/{y}/b", "y").SetAllowedMethods(http.MethodPost),
+   );
+
+   tests := []struct{
+      title string
+      httpMethod string
+      path string
+      status int
+      body string
+      allow string
+   } {
+      {"Literal", http.MethodGet, "/api/users/me", http.StatusOK, `"me"`, ""},
+      {"Falls Back To Template", http.MethodDelete, "/api/users/me", http.StatusOK, `"me"`, ""},
+      {"Template", http.MethodDelete, "/api/users/5", http.StatusOK, `"5"`, ""},
+      {"Neither", http.MethodPut, "/api/users/me", http.StatusMethodNotAllowed, `{"Success":false,"Code":405}`, "GET, HEAD, DELETE, OPTIONS"},
+      {"Neither Delete", http.MethodPost, "/api/users/me", http.StatusMethodNotAllowed, `{"Success":false,"Code":405}`, "GET, HEAD, DELETE, OPTIONS"},
+      {"Template Not Allowed", http.MethodGet, "/api/users/5", http.StatusMethodNotAllowed, `{"Success":false,"Code":405}`, "DELETE, OPTIONS"},
+      {"Equal Specificity GET", http.MethodGet, "/api/a/b", http.StatusOK, `"b"`, ""},
+      {"Equal Specificity POST", http.MethodPost, "/api/a/b", http.StatusOK, `"a"`, ""},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(test.httpMethod, test.path, nil);
+      response := httptest.NewRecorder();
+      router.ServeHTTP(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title, test.status, response.Code);
+         continue;
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.body) {
+         failTest(t, test.title, test.body, response.Body.String());
+      }
+
+      if (response.Header().Get("Allow") != test.allow) {
+         failTest(t, test.title + " (allow)", test.allow, response.Header().Get("Allow"));
+      }
+
+      // 405s need CORS headers too, or browsers will report a CORS failure instead.
+      if (test.status == http.StatusMethodNotAllowed) {
+         if (response.Header().Get("Access-Control-Allow-Origin") != "*") {
+            failTest(t, test.title + " (allow origin)", "*", response.Header().Get("Access-Control-Allow-Origin"));
+         }
+
+         if (response.Header().Get("Access-Control-Allow-Methods") != test.allow) {
+            failTest(t, test.title + " (allow methods)", test.allow, response.Header().Get("Access-Control-Allow-Methods"));
+         }
+      }
+   }
+}
+
+func TestRouterAmbiguousPaths(t *testing.T) {
+   factory := ApiMethodFactory{};
+
+   tests := []struct{
+      title string
+      first *ApiMethod
+      second *ApiMethod
+      valid bool
+   } {
+      {"Equally Specific", newPathEchoMethod(factory, "/a/{x}", "x"), newPathEchoMethod(factory, "/{y}/b", "y"), false},
+      {"Equally Specific Disjoint Methods", newPathEchoMethod(factory, "/a/{x}", "x").SetAllowedMethods(http.MethodGet), newPathEchoMethod(factory, "/{y}/b", "y").SetAllowedMethods(http.MethodPost), true},
+      {"Different Lengths", newPathEchoMethod(factory, "/a/{x}", "x"), newPathEchoMethod(factory, "/{y}/b/c", "y"), true},
+      {"Disjoint Literals", newPathEchoMethod(factory, "/a/{x}/c", "x"), newPathEchoMethod(factory, "/b/{y}/c", "y"), true},
+      {"More Specific", newPathEchoMethod(factory, "/a/{x}", "x"), factory.NewApiMethod("/{y}/{z}", handler_echoIntString, false, []ApiMethodParam{
+         ApiMethodParam{Name: "y", ParamType: API_PARAM_TYPE_INT, Required: true, Source: API_PARAM_SOURCE_PATH},
+         ApiMethodParam{Name: "z", ParamType: API_PARAM_TYPE_STRING, Required: true, Source: API_PARAM_SOURCE_PATH},
+      }), true},
+   };
+
+   for _, test := range(tests) {
+      func() {
+         defer func() {
+            r := recover();
+            if ((r == nil) != test.valid) {
+               failTest(t, test.title, test.valid, r == nil);
+            }
+         }();
+
+         factory.NewRouter("", test.first, test.second);
+      }();
+   }
+}
+
+// A method that responds with the value of its only path param.
+func newPathEchoMethod(factory ApiMethodFactory, path string, name string) *ApiMethod {
+   return factory.NewApiMethod(path, handler_echoString, false, []ApiMethodParam{
+      ApiMethodParam{Name: name, ParamType: API_PARAM_TYPE_STRING, Required: true, Source: API_PARAM_SOURCE_PATH},
+   });
+}
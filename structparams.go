@@ -0,0 +1,254 @@
+package goapi;
+
+import (
+   "fmt"
+   "reflect"
+   "strconv"
+   "strings"
+)
+
+// Instead of keeping a list of ApiMethodParams in sync with the handler's arguments,
+// a handler may take a single struct argument that describes all of its params through struct tags.
+// Each (exported) field with a "goapi" tag becomes a param, eg:
+//
+//    type ListArgs struct {
+//       Page int `goapi:"page,source=query,default=1"`
+//       Filter string `goapi:"filter,required"`
+//       Session string `goapi:"session,source=cookie"`
+//    }
+//
+// The tag is a comma-separated list that starts with the param's name (the field's name is used if empty).
+// The remaining options are:
+//  - source=<any|path|query|body|header|cookie> - The param's source (see API_PARAM_SOURCE_*).
+//  - required - The param is required.
+//  - allowempty - An empty string is a value rather than a missing param (see ApiMethodParam.AllowEmpty).
+//  - default=<value> - The value to use when the param is missing (cannot be used with required).
+//  - json - The entire JSON body is decoded into the field (see API_PARAM_TYPE_JSON).
+//  - min=<value>, max=<value> - See ApiMethodParam.Min and ApiMethodParam.Max.
+//  - minlen=<length>, maxlen=<length> - See ApiMethodParam.MinLength and ApiMethodParam.MaxLength.
+//  - pattern=<regex> - See ApiMethodParam.Pattern (the pattern cannot contain commas).
+//  - enum=<value>|<value>|... - See ApiMethodParam.Enum.
+//  - splitcommas - See ApiMethodParam.SplitCommas.
+//  - mincount=<count>, maxcount=<count> - See ApiMethodParam.MinCount and ApiMethodParam.MaxCount.
+// The param's type comes from the field's type.
+// Fields may also be pointers (eg *int), which will be nil when the param is missing.
+// Slice fields (eg []int) are lists (see ApiMethodParam.List) and their defaults are separated with "|" (eg "default=1|2").
+// Fields tagged with "-" (and untagged fields) are ignored.
+const STRUCT_TAG_NAME = "goapi"
+
+// Is |argType| a struct that holds params (has at least one field with a goapi tag).
+func isParamsStruct(argType reflect.Type) bool {
+   if (argType.Kind() != reflect.Struct) {
+      return false;
+   }
+
+   for i := 0; i < argType.NumField(); i++ {
+      tag, ok := argType.Field(i).Tag.Lookup(STRUCT_TAG_NAME);
+      if (ok && tag != "-") {
+         return true;
+      }
+   }
+
+   return false;
+}
+
+// If the handler takes a params struct, then build the method's params from it.
+// Will panic if the struct's tags are bad or if params were also explicitly given.
+func (method *ApiMethod) deriveStructParams() {
+   if (method.handler == nil || reflect.TypeOf(method.handler).Kind() != reflect.Func) {
+      return;
+   }
+
+   var handlerType reflect.Type = reflect.TypeOf(method.handler);
+   for i := 0; i < handlerType.NumIn(); i++ {
+      var argType reflect.Type = handlerType.In(i);
+      if (!isParamsStruct(argType)) {
+         continue;
+      }
+
+      if (method.paramsStruct != nil) {
+         method.log.Panic(fmt.Sprintf("API handler (%s) has more than one params struct", method.path));
+      }
+
+      if (len(method.params) != 0) {
+         method.log.Panic(fmt.Sprintf("API handler (%s) has a params struct (%s) and explicit params. Use only one.", method.path, argType.String()));
+      }
+
+      method.paramsStruct = argType;
+   }
+
+   if (method.paramsStruct == nil) {
+      return;
+   }
+
+   method.params = make([]ApiMethodParam, 0);
+   method.structFields = make([]int, 0);
+
+   for i := 0; i < method.paramsStruct.NumField(); i++ {
+      var field reflect.StructField = method.paramsStruct.Field(i);
+
+      tag, ok := field.Tag.Lookup(STRUCT_TAG_NAME);
+      if (!ok || tag == "-") {
+         continue;
+      }
+
+      param, err := parseParamTag(field, tag);
+      if (err != nil) {
+         method.log.Panic(fmt.Sprintf("API handler (%s) has a bad params struct field (%s.%s): %v", method.path, method.paramsStruct.String(), field.Name, err));
+      }
+
+      method.params = append(method.params, param);
+      method.structFields = append(method.structFields, i);
+   }
+}
+
+func parseParamTag(field reflect.StructField, tag string) (ApiMethodParam, error) {
+   if (!field.IsExported()) {
+      return ApiMethodParam{}, fmt.Errorf("Tagged fields must be exported");
+   }
+
+   var parts []string = strings.Split(tag, ",");
+   var param ApiMethodParam = ApiMethodParam{Name: strings.TrimSpace(parts[0])};
+   if (param.Name == "") {
+      param.Name = field.Name;
+   }
+
+   var isJSON bool = false;
+   var hasDefault bool = false;
+   var defaultValue string = "";
+   // Bounds need to wait until the type is known.
+   var bounds map[string]string = make(map[string]string);
+
+   for _, option := range(parts[1:]) {
+      option = strings.TrimSpace(option);
+
+      key, value, hasValue := strings.Cut(option, "=");
+      switch key {
+      case "required":
+         param.Required = true;
+      case "allowempty":
+         param.AllowEmpty = true;
+      case "json":
+         isJSON = true;
+      case "source":
+         var found bool = false;
+         for source, name := range(paramSourceNames) {
+            if (name == strings.TrimSpace(value)) {
+               param.Source = source;
+               found = true;
+            }
+         }
+
+         if (!found) {
+            return ApiMethodParam{}, fmt.Errorf("Unknown source: '%s'", value);
+         }
+      case "default":
+         if (!hasValue) {
+            return ApiMethodParam{}, fmt.Errorf("A default needs a value (default=<value>)");
+         }
+
+         hasDefault = true;
+         defaultValue = value;
+      case "min", "max":
+         if (!hasValue) {
+            return ApiMethodParam{}, fmt.Errorf("A %s needs a value (%s=<value>)", key, key);
+         }
+
+         bounds[key] = strings.TrimSpace(value);
+      case "minlen", "maxlen", "mincount", "maxcount":
+         number, err := strconv.Atoi(strings.TrimSpace(value));
+         if (err != nil) {
+            return ApiMethodParam{}, fmt.Errorf("Bad %s: '%s'", key, value);
+         }
+
+         switch key {
+         case "minlen":
+            param.MinLength = number;
+         case "maxlen":
+            param.MaxLength = number;
+         case "mincount":
+            param.MinCount = number;
+         case "maxcount":
+            param.MaxCount = number;
+         }
+      case "splitcommas":
+         param.SplitCommas = true;
+      case "pattern":
+         param.Pattern = value;
+      case "enum":
+         param.Enum = strings.Split(value, "|");
+      default:
+         return ApiMethodParam{}, fmt.Errorf("Unknown tag option: '%s'", option);
+      }
+   }
+
+   if (isJSON) {
+      param.ParamType = API_PARAM_TYPE_JSON;
+   } else {
+      var fieldType reflect.Type = field.Type;
+      if (fieldType.Kind() == reflect.Pointer) {
+         fieldType = fieldType.Elem();
+      } else if (fieldType.Kind() == reflect.Slice) {
+         fieldType = fieldType.Elem();
+         param.List = true;
+      }
+
+      paramType, ok := paramTypeForGoType(fieldType);
+      if (ok && fieldType != field.Type && !isScalarParamType(paramType)) {
+         ok = false;
+      }
+
+      if (!ok) {
+         return ApiMethodParam{}, fmt.Errorf("Unsupported field type (%s)", field.Type.String());
+      }
+
+      param.ParamType = paramType;
+   }
+
+   for key, bound := range(bounds) {
+      if (!isScalarParamType(param.ParamType)) {
+         return ApiMethodParam{}, fmt.Errorf("Files and JSON params cannot have a %s", key);
+      }
+
+      value, err := paramTypes[param.ParamType].parse(bound);
+      if (err != nil) {
+         return ApiMethodParam{}, fmt.Errorf("Bad %s for a %s: '%s' (%v)", key, paramTypes[param.ParamType].name, bound, err);
+      }
+
+      if (key == "min") {
+         param.Min = value;
+      } else {
+         param.Max = value;
+      }
+   }
+
+   // The rest of the default and constraint checks happen in ApiMethod.validate().
+   if (hasDefault) {
+      if (!isScalarParamType(param.ParamType)) {
+         return ApiMethodParam{}, fmt.Errorf("Files and JSON params cannot have defaults");
+      }
+
+      if (!param.List) {
+         value, err := paramTypes[param.ParamType].parse(strings.TrimSpace(defaultValue));
+         if (err != nil) {
+            return ApiMethodParam{}, fmt.Errorf("Bad default for a %s: '%s' (%v)", paramTypes[param.ParamType].name, defaultValue, err);
+         }
+
+         param.Default = value;
+      } else {
+         var values reflect.Value = reflect.MakeSlice(param.valueType(), 0, 0);
+         for _, element := range(strings.Split(defaultValue, "|")) {
+            value, err := paramTypes[param.ParamType].parse(strings.TrimSpace(element));
+            if (err != nil) {
+               return ApiMethodParam{}, fmt.Errorf("Bad default for a %s: '%s' (%v)", paramTypes[param.ParamType].name, element, err);
+            }
+
+            values = reflect.Append(values, reflect.ValueOf(value));
+         }
+
+         param.Default = values.Interface();
+      }
+   }
+
+   return param, nil;
+}
@@ -0,0 +1,246 @@
+package goapi;
+
+import (
+   "bytes"
+   "context"
+   "fmt"
+   "io"
+   "net/http"
+   "runtime/debug"
+   "sync"
+   "time"
+)
+
+// Handlers may take a context.Context (anywhere in their arguments) to find out when they should give up.
+// When an ApiMethod has a timeout, the context will be canceled once the timeout passes
+// and a 503 (Service Unavailable) will be sent (using the error responder) if the handler has not returned yet.
+// The error responder will get context.DeadlineExceeded.
+// Any response the handler writes after the timeout is thrown away.
+// Streamed responses are sent as they are written, so they are cut off (instead of replaced) at the timeout
+// (see timeoutWriter).
+
+// Returns this so you can chain.
+// Overrides the factory's timeout (see ApiMethodFactory.SetTimeout()), zero means no timeout.
+func (method *ApiMethod) SetTimeout(timeout time.Duration) *ApiMethod {
+   if (timeout < 0) {
+      method.log.Panic(fmt.Sprintf("API handler (%s) has a negative timeout", method.path));
+   }
+
+   method.timeout = timeout;
+   return method;
+}
+
+// Run the handler in its own goroutine and respond with an error if it runs past the method's timeout.
+// The response is buffered until the handler is done, so it can be thrown away if it is too late.
+// Streamed responses (flushed or copied from a reader) are not buffered (see timeoutWriter).
+func (method ApiMethod) serveWithTimeout(response http.ResponseWriter, request *http.Request) {
+   ctx, cancel := context.WithTimeout(request.Context(), method.timeout);
+   defer cancel();
+   request = request.WithContext(ctx);
+
+   var writer *timeoutWriter = &timeoutWriter{response: response, header: make(http.Header)};
+   var done chan struct{} = make(chan struct{});
+   var panics chan interface{} = make(chan interface{}, 1);
+
+   go func() {
+      // Panics need to be passed back to the original goroutine to be handled.
+      defer func() {
+         recovered := recover();
+         if (recovered != nil) {
+            if (recovered != http.ErrAbortHandler) {
+               recovered = PanicError{recovered, debug.Stack()};
+            }
+
+            panics <- recovered;
+         }
+      }();
+
+      method.serve(writer, request);
+      writer.finish();
+      close(done);
+   }();
+
+   select {
+   case recovered := <-panics:
+      writer.timeout();
+      panic(recovered);
+   case <-done:
+      writer.commit();
+      return;
+   case <-ctx.Done():
+   }
+
+   // The handler may have panicked just as the timeout passed (select picks randomly when both are ready).
+   select {
+   case recovered := <-panics:
+      writer.timeout();
+      panic(recovered);
+   default:
+   }
+
+   finished, committed := writer.timeout();
+
+   // The handler finished just as the timeout passed, its whole response is ready.
+   if (finished) {
+      writer.commit();
+      return;
+   }
+
+   method.log.Warn(fmt.Sprintf("API handler (%s) did not finish before its timeout (%v)", method.path, method.timeout));
+
+   if (committed) {
+      // Part of the response already went out, so all we can do is cut it off.
+      panic(http.ErrAbortHandler);
+   }
+
+   method.sendErrorResponse(ctx.Err(), http.StatusServiceUnavailable, response, request);
+}
+
+// A ResponseWriter that holds the entire response until the handler is done.
+// If the handler flushes or streams (io.Copy() from a reader, which includes http.ServeContent()),
+// then the response is committed: everything so far is sent and the rest goes straight to the real response.
+// A committed response can no longer be replaced by a 503, so it is cut off at the timeout instead.
+// After the timeout, all writes are ignored.
+type timeoutWriter struct {
+   mutex sync.Mutex
+   response http.ResponseWriter
+   header http.Header
+   body bytes.Buffer
+   status int
+   committed bool
+   // The handler is done and the whole response is here.
+   finished bool
+   timedOut bool
+}
+
+func (writer *timeoutWriter) Header() http.Header {
+   return writer.header;
+}
+
+func (writer *timeoutWriter) Write(data []byte) (int, error) {
+   writer.mutex.Lock();
+   defer writer.mutex.Unlock();
+
+   if (writer.timedOut) {
+      return 0, http.ErrHandlerTimeout;
+   }
+
+   if (writer.committed) {
+      return writer.response.Write(data);
+   }
+
+   if (writer.status == 0) {
+      writer.status = http.StatusOK;
+   }
+
+   return writer.body.Write(data);
+}
+
+func (writer *timeoutWriter) WriteHeader(status int) {
+   writer.mutex.Lock();
+   defer writer.mutex.Unlock();
+
+   if (writer.timedOut || writer.committed || writer.status != 0) {
+      return;
+   }
+
+   writer.status = status;
+}
+
+// Send everything so far and stop buffering.
+func (writer *timeoutWriter) Flush() {
+   writer.mutex.Lock();
+   defer writer.mutex.Unlock();
+
+   if (writer.timedOut) {
+      return;
+   }
+
+   writer.commitLocked();
+
+   flusher, ok := writer.response.(http.Flusher);
+   if (ok) {
+      flusher.Flush();
+   }
+}
+
+// Stream |reader| straight into the real response (used by io.Copy()).
+func (writer *timeoutWriter) ReadFrom(reader io.Reader) (int64, error) {
+   writer.mutex.Lock();
+   if (writer.timedOut) {
+      writer.mutex.Unlock();
+      return 0, http.ErrHandlerTimeout;
+   }
+
+   writer.commitLocked();
+   writer.mutex.Unlock();
+
+   // Write() (not ReadFrom()) takes the lock for each chunk, so the timeout can still cut in.
+   return io.Copy(struct{ io.Writer }{writer}, reader);
+}
+
+// The handler is done, unless it is already too late.
+// Returns false if it was too late.
+func (writer *timeoutWriter) finish() bool {
+   writer.mutex.Lock();
+   defer writer.mutex.Unlock();
+
+   if (!writer.timedOut) {
+      writer.finished = true;
+   }
+
+   return writer.finished;
+}
+
+// Settle if a response made it out in time (see serve()).
+// Only responses from serveWithTimeout() can be too late.
+func finishedInTime(response http.ResponseWriter) bool {
+   writer, ok := response.(*timeoutWriter);
+   if (!ok) {
+      return true;
+   }
+
+   return writer.finish();
+}
+
+// Stop all writes, unless the handler already finished.
+// Returns if the handler finished and if part of the response was already sent.
+func (writer *timeoutWriter) timeout() (bool, bool) {
+   writer.mutex.Lock();
+   defer writer.mutex.Unlock();
+
+   if (!writer.finished) {
+      writer.timedOut = true;
+   }
+
+   return writer.finished, writer.committed;
+}
+
+// Send the (rest of the) response.
+func (writer *timeoutWriter) commit() {
+   writer.mutex.Lock();
+   defer writer.mutex.Unlock();
+
+   writer.commitLocked();
+}
+
+// The lock must be held.
+func (writer *timeoutWriter) commitLocked() {
+   if (writer.committed) {
+      return;
+   }
+
+   writer.committed = true;
+
+   for key, values := range(writer.header) {
+      writer.response.Header()[key] = values;
+   }
+
+   if (writer.status == 0) {
+      writer.status = http.StatusOK;
+   }
+
+   writer.response.WriteHeader(writer.status);
+   writer.response.Write(writer.body.Bytes());
+   writer.body.Reset();
+}
@@ -0,0 +1,216 @@
+package goapi;
+
+import (
+   "context"
+   "fmt"
+   "io"
+   "net/http"
+   "net/http/httptest"
+   "strings"
+   "testing"
+   "time"
+);
+
+func TestContextParam(t *testing.T) {
+   factory := ApiMethodFactory{};
+   factory.SetTimeout(time.Minute);
+
+   handler := func(someInt int, ctx context.Context) interface{} {
+      _, hasDeadline := ctx.Deadline();
+      return hasDeadline;
+   };
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+      response string
+   } {
+      {"Factory Timeout", factory.NewApiMethod("/context", handler, false, []ApiMethodParam{
+         ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT},
+      }), `true`},
+      {"No Timeout", factory.NewApiMethod("/context", handler, false, []ApiMethodParam{
+         ApiMethodParam{Name: "someInt", ParamType: API_PARAM_TYPE_INT},
+      }).SetTimeout(0), `false`},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodGet, "/context?someInt=1", nil);
+      response := httptest.NewRecorder();
+      test.method.Middleware()(response, request);
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
+func TestTimeout(t *testing.T) {
+   var reported interface{} = nil;
+
+   factory := ApiMethodFactory{};
+   factory.SetTimeout(20 * time.Millisecond);
+   factory.SetPanicReporter(func(recovered interface{}, stack []byte, request *http.Request) {
+      reported = recovered;
+   });
+
+   slow := factory.NewApiMethod("/slow", func(ctx context.Context, response http.ResponseWriter) interface{} {
+      // Take a little while to notice.
+      <-ctx.Done();
+      time.Sleep(10 * time.Millisecond);
+      response.Header().Set("X-Late", "true");
+      return "late";
+   }, false, []ApiMethodParam{});
+
+   fast := factory.NewApiMethod("/fast", func() (interface{}, int) {
+      return "fast", http.StatusCreated;
+   }, false, []ApiMethodParam{});
+
+   typed := NewTypedApiMethod(factory, "/typed", false, func(ctx context.Context, request struct{}) (interface{}, error) {
+      <-ctx.Done();
+      time.Sleep(10 * time.Millisecond);
+      return nil, ctx.Err();
+   });
+
+   panics := factory.NewApiMethod("/panic", func() { panic("boom"); }, false, []ApiMethodParam{});
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+      status int
+      response string
+   } {
+      {"Slow", slow, http.StatusServiceUnavailable, `{"Success":false,"Code":503}`},
+      {"Fast", fast, http.StatusCreated, `"fast"`},
+      {"Typed", typed, http.StatusServiceUnavailable, `{"Success":false,"Code":503}`},
+      {"Panic", panics, http.StatusInternalServerError, `{"Success":false,"Code":500}`},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodGet, "/", nil);
+      response := httptest.NewRecorder();
+      test.method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title + " (status)", test.status, response.Code);
+      }
+
+      if (response.Header().Get("Content-Type") != "application/json; charset=UTF-8") {
+         failTest(t, test.title + " (content type)", "application/json; charset=UTF-8", response.Header().Get("Content-Type"));
+      }
+
+      if (response.Header().Get("X-Late") != "") {
+         failTest(t, test.title + " (late header)", "", response.Header().Get("X-Late"));
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+
+   if (reported != "boom") {
+      failTest(t, "Panic (reported)", "boom", reported);
+   }
+}
+
+type testOnlyReader struct {
+   io.Reader
+}
+
+// Handlers that outlive their test should not write to stdout (which examples capture).
+type quietLogger struct {
+   ConsoleLogger
+}
+
+func (log quietLogger) Fatal(msg string) {}
+func (log quietLogger) Error(msg string) {}
+func (log quietLogger) ErrorE(msg string, err error) {}
+func (log quietLogger) Warn(msg string) {}
+func (log quietLogger) WarnE(msg string, err error) {}
+func (log quietLogger) Debug(msg string) {}
+
+func TestTimeoutStreaming(t *testing.T) {
+   factory := ApiMethodFactory{};
+   factory.SetLogger(quietLogger{});
+   factory.SetTimeout(20 * time.Millisecond);
+
+   stream := factory.NewApiMethod("/stream", func() interface{} {
+      return testOnlyReader{strings.NewReader("streamed")};
+   }, false, []ApiMethodParam{});
+
+   content := factory.NewApiMethod("/content", func() interface{} {
+      return strings.NewReader("content");
+   }, false, []ApiMethodParam{});
+
+   flushed := factory.NewApiMethod("/flushed", func(ctx context.Context, response http.ResponseWriter) {
+      response.WriteHeader(http.StatusAccepted);
+      response.Write([]byte("partial"));
+      response.(http.Flusher).Flush();
+      <-ctx.Done();
+      time.Sleep(10 * time.Millisecond);
+      response.Write([]byte(" late"));
+   }, false, []ApiMethodParam{});
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+      status int
+      response string
+      aborted bool
+   } {
+      {"Stream", stream, http.StatusOK, "streamed", false},
+      {"Content", content, http.StatusOK, "content", false},
+      {"Flushed", flushed, http.StatusAccepted, "partial", true},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodGet, "/", nil);
+      response := httptest.NewRecorder();
+
+      var recovered interface{} = nil;
+      func() {
+         defer func() {
+            recovered = recover();
+         }();
+
+         test.method.Middleware()(response, request);
+      }();
+
+      if (test.aborted != (recovered == http.ErrAbortHandler)) {
+         failTest(t, test.title + " (aborted)", test.aborted, recovered);
+      }
+
+      if (response.Code != test.status) {
+         failTest(t, test.title + " (status)", test.status, response.Code);
+      }
+
+      if (response.Body.String() != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
+// Whichever of the handler finishing and the timeout happens first wins.
+func TestTimeoutWriterFinish(t *testing.T) {
+   var finished *timeoutWriter = &timeoutWriter{response: httptest.NewRecorder(), header: make(http.Header)};
+   finished.Write([]byte("done"));
+   finished.finish();
+
+   done, committed := finished.timeout();
+   if (!done || committed) {
+      failTest(t, "Finished First", "finished and not committed", fmt.Sprintf("%v %v", done, committed));
+   }
+
+   var late *timeoutWriter = &timeoutWriter{response: httptest.NewRecorder(), header: make(http.Header)};
+   late.Write([]byte("partial"));
+
+   done, committed = late.timeout();
+   late.finish();
+   if (done || committed || late.finished) {
+      failTest(t, "Timeout First", "not finished and not committed", fmt.Sprintf("%v %v %v", done, committed, late.finished));
+   }
+
+   _, err := late.Write([]byte("late"));
+   if (err != http.ErrHandlerTimeout) {
+      failTest(t, "Timeout First (write)", http.ErrHandlerTimeout, err);
+   }
+}
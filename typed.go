@@ -0,0 +1,159 @@
+package goapi;
+
+import (
+   "context"
+   "fmt"
+   "net/http"
+   "reflect"
+)
+
+// Make an ApiMethod with a typed handler.
+// Typed handlers get compile-time type checking and the handler itself is invoked without reflection.
+// Filling in a params struct Req still uses reflection on every request (the fields are set like any other params struct),
+// only the field list is worked out once here.
+// Otherwise, they behave like any other ApiMethod (authentication, serialization, error responses, etc).
+//
+// Req describes the request's params:
+//  - If Req is a params struct (has fields with goapi tags, see structparams.go), then its fields are filled in from the request.
+//  - If Req is an empty struct, then there are no params.
+//  - Otherwise, the request's JSON body (if any) is decoded into Req.
+// The returned Resp is handled just like an interface{} returned from a normal handler
+// (a nil pointer Resp is a nil response) and the returned error is handled just like an error returned from a normal handler.
+//
+// |ctx| is the request's context.
+// For authenticated methods, see ContextUserId(), ContextUserName(), and ContextToken().
+func NewTypedApiMethod[Req any, Resp any](factory ApiMethodFactory, path string, auth bool, handler func(ctx context.Context, request Req) (Resp, error)) *ApiMethod {
+   var reqType reflect.Type = reflect.TypeOf((*Req)(nil)).Elem();
+
+   var params []ApiMethodParam = nil;
+   if (!isParamsStruct(reqType) && !(reqType.Kind() == reflect.Struct && reqType.NumField() == 0)) {
+      params = []ApiMethodParam{
+         ApiMethodParam{Name: "body", ParamType: API_PARAM_TYPE_JSON, Required: false},
+      };
+   }
+
+   var method ApiMethod = factory.buildApiMethod(path, handler, auth, params);
+
+   // Typed handlers have no way to take injected values, so do not let them be silently skipped.
+   if (len(method.injectors) > 0) {
+      method.log.Panic(fmt.Sprintf("API handler (%s) is typed, but the factory has injectors (typed handlers cannot use injectors)", path));
+   }
+
+   // A nil func is not a nil interface{}, make sure validation catches it.
+   if (handler == nil) {
+      method.handler = nil;
+   }
+
+   var bind typedBinder[Req] = nil;
+
+   // A nil *Resp is a nil response, not a non-nil interface{} holding a nil pointer
+   // (which would, for example, get read from if Resp is a reader).
+   var respIsPointer bool = reflect.TypeOf((*Resp)(nil)).Elem().Kind() == reflect.Pointer;
+   var nilResp Resp;
+
+   method.typedHandler = func(method ApiMethod, response http.ResponseWriter, request *http.Request) (interface{}, int, string, error) {
+      var typedRequest Req;
+
+      err := bind(method, request, &typedRequest);
+      if (err != nil) {
+         return method.badRequestResponse(err, request);
+      }
+
+      typedResponse, err := handler(request.Context(), typedRequest);
+      if (respIsPointer && any(typedResponse) == any(nilResp)) {
+         return nil, 0, method.contentType, err;
+      }
+
+      return typedResponse, 0, method.contentType, err;
+   };
+
+   method.deriveStructParams();
+   method.validate();
+   method.params = prepareConstraints(method.params);
+
+   // Worked out once the params are final.
+   bind = newTypedBinder[Req](method);
+
+   return &method;
+}
+
+// Fills in the request for a typed handler.
+// Like createArguments(), all failures are returned as ParamErrors.
+type typedBinder[Req any] func(method ApiMethod, request *http.Request, typedRequest *Req) error
+
+// Pick how to fill in a Req once.
+// Params structs still reflect per request (see newStructBinder()), JSON bodies and empty structs do not.
+func newTypedBinder[Req any](method ApiMethod) typedBinder[Req] {
+   if (method.paramsStruct != nil) {
+      return newStructBinder[Req](method);
+   }
+
+   if (len(method.params) == 0) {
+      return func(method ApiMethod, request *http.Request, typedRequest *Req) error {
+         return nil;
+      };
+   }
+
+   return bindTypedBody[Req];
+}
+
+// A params struct field and the type it is fetched as.
+type typedField struct {
+   index int
+   fieldType reflect.Type
+}
+
+// The fields (and their types) are found once, but each request still sets them through reflect.Value.
+func newStructBinder[Req any](method ApiMethod) typedBinder[Req] {
+   var fields []typedField = make([]typedField, 0, len(method.structFields));
+   for _, fieldIndex := range(method.structFields) {
+      fields = append(fields, typedField{fieldIndex, method.paramsStruct.Field(fieldIndex).Type});
+   }
+
+   return func(method ApiMethod, request *http.Request, typedRequest *Req) error {
+      var body *jsonBody = method.newJSONBody(request);
+      var structValue reflect.Value = reflect.ValueOf(typedRequest).Elem();
+      var errs ParamErrors = nil;
+
+      for i, field := range(fields) {
+         val, paramErrs := method.fetchParam(i, field.fieldType, request, body);
+         if (paramErrs != nil) {
+            errs = append(errs, paramErrs...);
+         } else {
+            structValue.Field(field.index).Set(val);
+         }
+      }
+
+      if (body.tooLarge()) {
+         return body.err;
+      }
+
+      if (len(errs) > 0) {
+         return errs;
+      }
+
+      return nil;
+   };
+}
+
+// Decode the JSON body straight into the request.
+func bindTypedBody[Req any](method ApiMethod, request *http.Request, typedRequest *Req) error {
+   if (!isJSONRequest(request)) {
+      return nil;
+   }
+
+   var body *jsonBody = method.newJSONBody(request);
+
+   _, err := body.decodeInto(typedRequest);
+   if (body.tooLarge()) {
+      return body.err;
+   }
+
+   if (err != nil) {
+      var param ApiMethodParam = method.params[0];
+      method.log.WarnE(fmt.Sprintf("Unable to decode JSON body parameter (%s)", param.Name), err);
+      return ParamErrors{*param.badJSONError()};
+   }
+
+   return nil;
+}
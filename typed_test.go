@@ -0,0 +1,155 @@
+package goapi;
+
+import (
+   "bytes"
+   "context"
+   "fmt"
+   "net/http"
+   "net/http/httptest"
+   "strings"
+   "sync"
+   "testing"
+);
+
+type testTypedResponse struct {
+   Message string
+}
+
+func TestTypedApiMethod(t *testing.T) {
+   factory := ApiMethodFactory{};
+   factory.SetTokenValidator(func(token string, log Logger) (int, string, error) {
+      return 7, "alice", nil;
+   });
+
+   params := NewTypedApiMethod(factory, "/users/{id}", true, func(ctx context.Context, request testParamsStruct) (testTypedResponse, error) {
+      userName, _ := ContextUserName(ctx);
+      return testTypedResponse{fmt.Sprintf("%s %d %d %s", userName, request.Id, request.Page, request.Filter)}, nil;
+   });
+
+   body := NewTypedApiMethod(factory, "/body", false, func(ctx context.Context, request testJSONBody) (*testTypedResponse, error) {
+      _, ok := ContextUserId(ctx);
+      return &testTypedResponse{fmt.Sprintf("%v %d %v", ok, request.Count, request.Names)}, nil;
+   });
+
+   empty := NewTypedApiMethod(factory, "/empty", false, func(ctx context.Context, request struct{}) (interface{}, error) {
+      return nil, fmt.Errorf("Some error");
+   });
+
+   nilResponse := NewTypedApiMethod(factory, "/nil", false, func(ctx context.Context, request struct{}) (*bytes.Reader, error) {
+      return nil, nil;
+   });
+
+   apiError := NewTypedApiMethod(factory, "/apiError", false, func(ctx context.Context, request struct{}) (interface{}, error) {
+      return nil, APIError{Status: http.StatusForbidden, Code: "no", Message: "Not allowed"};
+   });
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+      path string
+      body string
+      status int
+      response string
+   } {
+      {"Params", params, "/users/5?Filter=abc", "", http.StatusOK, `{"Message":"alice 5 1 abc"}`},
+      {"Params Missing", params, "/users/5", "", http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"Filter","Code":"missing","Message":"is required"}]}`},
+      {"Body", body, "/body", `{"Count": 3, "Names": ["a"]}`, http.StatusOK, `{"Message":"false 3 [a]"}`},
+      {"Body Empty", body, "/body", ``, http.StatusOK, `{"Message":"false 0 []"}`},
+      {"Body Bad", body, "/body", `{"Count": "a"}`, http.StatusBadRequest, `{"Success":false,"Code":400,"Params":[{"Name":"body","Code":"bad-type","Message":"the request body must be valid JSON of the expected type"}]}`},
+      {"Error", empty, "/empty", ``, http.StatusInternalServerError, `{"Success":false,"Code":500}`},
+      {"Nil Response", nilResponse, "/nil", ``, http.StatusOK, `null`},
+      {"API Error", apiError, "/apiError", ``, http.StatusForbidden, `{"Success":false,"Code":403,"ErrorCode":"no","Message":"Not allowed"}`},
+   };
+
+   for _, test := range(tests) {
+      request := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body));
+      request.Header.Set("Content-Type", "application/json");
+      request.Header.Set("Authorization", "Bearer TOKEN");
+
+      response := httptest.NewRecorder();
+      test.method.Middleware()(response, request);
+
+      if (response.Code != test.status) {
+         failTest(t, test.title, test.status, response.Code);
+         continue;
+      }
+
+      if (strings.TrimSpace(response.Body.String()) != test.response) {
+         failTest(t, test.title, test.response, response.Body.String());
+      }
+   }
+}
+
+// The binder is worked out once, so make sure requests do not leak into each other.
+func TestTypedApiMethodRepeated(t *testing.T) {
+   factory := ApiMethodFactory{};
+
+   params := NewTypedApiMethod(factory, "/users/{id}", false, func(ctx context.Context, request testParamsStruct) (string, error) {
+      return fmt.Sprintf("%d %d %s", request.Id, request.Page, request.Filter), nil;
+   });
+
+   body := NewTypedApiMethod(factory, "/body", false, func(ctx context.Context, request testJSONBody) (string, error) {
+      return fmt.Sprintf("%d %v", request.Count, request.Names), nil;
+   });
+
+   tests := []struct{
+      title string
+      method *ApiMethod
+      path string
+      body string
+      response string
+   } {
+      {"Params", params, "/users/5?Filter=abc&page=3", "", `"5 3 abc"`},
+      {"Params Default", params, "/users/6?Filter=def", "", `"6 1 def"`},
+      {"Body", body, "/body", `{"Count": 3, "Names": ["a"]}`, `"3 [a]"`},
+      {"Body Partial", body, "/body", `{"Count": 4}`, `"4 []"`},
+   };
+
+   var group sync.WaitGroup;
+   for i := 0; i < 10; i++ {
+      for _, test := range(tests) {
+         group.Add(1);
+         go func(title string, method *ApiMethod, path string, body string, expected string) {
+            defer group.Done();
+
+            request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body));
+            request.Header.Set("Content-Type", "application/json");
+
+            response := httptest.NewRecorder();
+            method.Middleware()(response, request);
+
+            if (strings.TrimSpace(response.Body.String()) != expected) {
+               t.Errorf("%s: Expected: %s, Got: %s", title, expected, response.Body.String());
+            }
+         }(test.title, test.method, test.path, test.body, test.response);
+      }
+   }
+   group.Wait();
+}
+
+func TestTypedApiMethodValidation(t *testing.T) {
+   factory := ApiMethodFactory{};
+
+   func() {
+      defer func() {
+         if (recover() == nil) {
+            t.Errorf("Nil Handler: Failed to Panic");
+         }
+      }();
+
+      var handler func(context.Context, struct{}) (interface{}, error) = nil;
+      NewTypedApiMethod(factory, "/nil", false, handler);
+   }();
+
+   func() {
+      defer func() {
+         if (recover() == nil) {
+            t.Errorf("Missing Placeholder: Failed to Panic");
+         }
+      }();
+
+      NewTypedApiMethod(factory, "/users", false, func(ctx context.Context, request testParamsStruct) (interface{}, error) {
+         return nil, nil;
+      });
+   }();
+}